@@ -6,92 +6,125 @@ package main
 
 import (
 	"compress/gzip"
-	"encoding/csv"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"strconv"
-)
+	"path/filepath"
+	"strings"
 
-// countData holds count data for a set of named samples each with a collection
-// of features.
-type countData struct {
-	// names is the names of the samples.
-	names []string
+	"github.com/kortschak/smeargol/smear"
+)
 
-	// counts holds a set of counts for
-	// features keyed by the map key.
-	// The length of each []float64
-	// must match the length of names
-	// and indexing into the []float64
-	// reflects indexing into names.
-	counts map[string][]float64
+// quantFiles is the set of per-sample quantification file names that are
+// read from a directory of sample directories.
+var quantFiles = []string{"quant.sf", "quant.sf.gz", "abundance.tsv", "abundance.tsv.gz"}
 
-	// geneIDs and geneIdx are mappings
-	// between internal gene index and
-	// external gene identifier.
-	geneIDs []string
-	geneIdx map[string]int
-}
-
-// mappingCounts returns the count data held in the file at path.
-func mappingCounts(path string) (*countData, error) {
-	f, err := os.Open(path)
+// mappingCounts returns the count data held at path in the given format
+// using the quant quantification measure. If path is a directory, each
+// subdirectory is expected to hold a salmon quant.sf or kallisto
+// abundance.tsv file for the sample named by the subdirectory, and the
+// samples are merged into a single smear.CountData. If format is auto,
+// the format is determined from the header of each file.
+func mappingCounts(ctx context.Context, path, format, quant string) (*smear.CountData, error) {
+	fi, err := os.Stat(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	if !fi.IsDir() {
+		return countsFile(ctx, path, sampleName(path), format, quant, true)
+	}
 
-	r, err := gzip.NewReader(f)
+	dirs, err := os.ReadDir(path)
 	if err != nil {
 		return nil, err
 	}
+	var samples []*smear.CountData
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		file, err := quantFile(filepath.Join(path, d.Name()))
+		if err != nil {
+			return nil, err
+		}
+		c, err := countsFile(ctx, file, d.Name(), format, quant, strings.HasSuffix(file, ".gz"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		samples = append(samples, c)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no sample quantification files found in %s", path)
+	}
+	return smear.MergeCounts(samples), nil
+}
 
-	c := csv.NewReader(r)
-	c.Comma = '\t'
-	c.Comment = '#'
+// quantFile returns the path of the quantification file in dir.
+func quantFile(dir string) (string, error) {
+	for _, name := range quantFiles {
+		path := filepath.Join(dir, name)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no quantification file found in %s", dir)
+}
 
-	labels, err := c.Read()
-	if err != nil {
-		if err == io.EOF {
-			return nil, io.ErrUnexpectedEOF
+// sampleName returns the sample name for a single sample quantification
+// file at path. Files named with a standard quantification file name are
+// named by their directory, otherwise the file name without extensions is
+// used.
+func sampleName(path string) string {
+	base := filepath.Base(path)
+	for _, name := range quantFiles {
+		if base == name {
+			return filepath.Base(filepath.Dir(path))
 		}
-		return nil, err
 	}
-	if labels[0] != "Geneid" {
-		return nil, fmt.Errorf(`unexpected first column name: %q != "Geneid"`, labels[0])
+	if i := strings.IndexByte(base, '.'); i > 0 {
+		base = base[:i]
 	}
-	samples := labels[1:]
+	return base
+}
 
-	data := make(map[string][]float64, len(samples))
-	geneIdx := make(map[string]int)
-	var geneIDs []string
+// countsFile returns the count data held in the file at path. The name
+// is used as the sample name for single sample formats.
+func countsFile(ctx context.Context, path, name, format, quant string, compressed bool) (*smear.CountData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-	c.ReuseRecord = true
-	for {
-		counts, err := c.Read()
+	var r io.Reader = f
+	if compressed {
+		r, err = gzip.NewReader(f)
 		if err != nil {
-			if err != io.EOF {
-				return nil, err
-			}
-			break
-		}
-		geneid := counts[0]
-		geneIdx[geneid] = len(geneIDs)
-		geneIDs = append(geneIDs, geneid)
-		for i, f := range counts[1:] {
-			v, err := strconv.ParseFloat(f, 64)
-			if err != nil {
-				return nil, fmt.Errorf("error parsing value for %q in sample %q: %v", geneid, samples[i], err)
-			}
-			data[geneid] = append(data[geneid], v)
+			return nil, err
 		}
 	}
+	return smear.ReadCounts(ctx, r, name, format, quant)
+}
 
-	return &countData{
-		names:   samples,
-		counts:  data,
-		geneIDs: geneIDs,
-		geneIdx: geneIdx,
-	}, nil
+// transcriptGenes returns a mapping from transcript identifiers to gene
+// identifiers held in the gzip compressed file at path. See
+// smear.ReadTranscriptGenes for the accepted formats.
+func transcriptGenes(path string, strip bool) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return smear.ReadTranscriptGenes(z, strip)
 }
@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"math/big"
 	"os"
 	"strings"
 	"sync"
@@ -21,12 +20,13 @@ import (
 	"gonum.org/v1/gonum/graph/iterator"
 
 	"github.com/kortschak/gogo"
+	"github.com/kortschak/smeargol/smear"
 )
 
 type debugWriter struct {
-	ontology *gogo.Graph
-	ontoData []map[string]ontoCounts
-	data     *countData
+	ontology *smear.Ontology
+	painting *smear.Painting
+	data     *smear.CountData
 
 	mu     sync.Mutex
 	depths map[string]int
@@ -34,39 +34,53 @@ type debugWriter struct {
 	buffers []bytes.Buffer
 }
 
-func newDebugWriter(ontology *gogo.Graph, ontoData []map[string]ontoCounts, data *countData) *debugWriter {
+func newDebugWriter(ontology *smear.Ontology, painting *smear.Painting, data *smear.CountData) *debugWriter {
 	return &debugWriter{
 		ontology: ontology,
 		depths:   make(map[string]int),
-		ontoData: ontoData,
+		painting: painting,
 		data:     data,
-		buffers:  make([]bytes.Buffer, len(ontoData)),
+		buffers:  make([]bytes.Buffer, len(painting.Roots())),
 	}
 }
 
-func (d *debugWriter) record(aspect, depth int, root, term rdf.Term) {
+func (d *debugWriter) record(aspect, depth int, root rdf.Term, term string) {
 	if d == nil {
 		return
 	}
 	d.mu.Lock()
-	d.depths[term.Value] = depth
+	d.depths[term] = depth
 	d.mu.Unlock()
-	counts, ok := d.ontoData[aspect][term.Value]
+	vector, ok := paintedSets(d.painting, aspect, term, len(d.data.Names))
 	if !ok {
 		return
 	}
-	fmt.Fprintf(&d.buffers[aspect], "%s\t%s\t%s\t%d", strip(term.Value, "<obo:", ">"), strip(root.Value, "<obo:", ">"), nameSpaceOf(term, d.ontology), depth)
-	for _, v := range counts.vector {
-		fmt.Fprintf(&d.buffers[aspect], "\t%0*b", len(d.data.geneIDs), &v)
+	fmt.Fprintf(&d.buffers[aspect], "%s\t%s\t%s\t%d", strip(term, "<obo:", ">"), strip(root.Value, "<obo:", ">"), d.ontology.Namespace(term), depth)
+	for _, v := range vector {
+		fmt.Fprintf(&d.buffers[aspect], "\t%s", v.Binary(len(d.data.GeneIDs)))
 	}
 	fmt.Fprintln(&d.buffers[aspect])
 }
 
+// paintedSets returns the genes painted onto term in each of the samples
+// for the aspect with index root. If the term was not painted, ok is
+// returned false.
+func paintedSets(p *smear.Painting, root int, term string, samples int) (sets []smear.GeneSet, ok bool) {
+	sets = make([]smear.GeneSet, samples)
+	for i := range sets {
+		sets[i], ok = p.Genes(root, term, i)
+		if !ok {
+			return nil, false
+		}
+	}
+	return sets, true
+}
+
 func (d *debugWriter) flush() {
 	if d == nil {
 		return
 	}
-	fmt.Printf("/*\ngo_term\tgo_root\tgo_aspect\tdepth\t%s\n", strings.Join(d.data.names, "\t"))
+	fmt.Printf("/*\ngo_term\tgo_root\tgo_aspect\tdepth\t%s\n", strings.Join(d.data.Names, "\t"))
 	for i := range d.buffers {
 		_, err := io.Copy(os.Stdout, &d.buffers[i])
 		if err != nil {
@@ -75,7 +89,7 @@ func (d *debugWriter) flush() {
 	}
 	fmt.Println("*/")
 
-	g := newDebugGraph(d.ontology, d.depths, d.data, d.ontoData)
+	g := newDebugGraph(d.ontology.Graph(), d.depths, d.data, d.painting)
 	b, err := dot.MarshalMulti(g, "debug", "", "\t")
 	if err != nil {
 		log.Println(err)
@@ -88,11 +102,11 @@ type debugGraph struct {
 	*gogo.Graph
 
 	depths   map[string]int
-	data     *countData
-	ontoData []map[string]ontoCounts
+	data     *smear.CountData
+	painting *smear.Painting
 }
 
-func newDebugGraph(g *gogo.Graph, depths map[string]int, data *countData, ontoData []map[string]ontoCounts) *debugGraph {
+func newDebugGraph(g *gogo.Graph, depths map[string]int, data *smear.CountData, painting *smear.Painting) *debugGraph {
 	c := gogo.NewGraph()
 	it := g.AllStatements()
 	for it.Next() {
@@ -107,7 +121,7 @@ func newDebugGraph(g *gogo.Graph, depths map[string]int, data *countData, ontoDa
 		Graph:    c,
 		depths:   depths,
 		data:     data,
-		ontoData: ontoData,
+		painting: painting,
 	}
 }
 
@@ -135,7 +149,7 @@ func (g *debugGraph) filtered(it graph.Nodes) graph.Nodes {
 		term := it.Node().(rdf.Term)
 		switch {
 		case strings.HasPrefix(term.Value, "<ensembl:"):
-			counts, ok := g.data.counts[strip(term.Value, "<ensembl:", ">")]
+			counts, ok := g.data.Counts[strip(term.Value, "<ensembl:", ">")]
 			if !ok {
 				continue
 			}
@@ -149,22 +163,17 @@ func (g *debugGraph) filtered(it graph.Nodes) graph.Nodes {
 				}
 			}
 		case strings.HasPrefix(term.Value, "<obo:GO_"):
-			for _, aspect := range g.ontoData {
-				counts, ok := aspect[term.Value]
+			for aspect := range g.painting.Roots() {
+				bits, ok := paintedSets(g.painting, aspect, term.Value, len(g.data.Names))
 				if !ok {
 					continue
 				}
-				for i := range counts.vector {
-					v := &counts.vector[i]
-					if v.BitLen() != 0 {
-						bits := make([]*big.Int, len(counts.vector))
-						for j := range counts.vector {
-							bits[j] = &counts.vector[j]
-						}
+				for _, v := range bits {
+					if v.Len() != 0 {
 						dotNodes = append(dotNodes, &goTermNode{
 							Term:  term,
 							depth: g.depths[term.Value],
-							wid:   len(g.data.geneIDs),
+							wid:   len(g.data.GeneIDs),
 							bits:  bits,
 						})
 						break
@@ -194,10 +203,14 @@ func (g *debugGraph) Lines(uid, vid int64) graph.Lines {
 				},
 			})
 		default:
+			label := "subclass_of"
+			if name, ok := smear.RelationName(l.Predicate.Value); ok && name != "is_a" {
+				label = name
+			}
 			lines = append(lines, dotLine{
 				Statement: l,
 				attrs: []encoding.Attribute{
-					{Key: "label", Value: "subclass_of"},
+					{Key: "label", Value: label},
 				},
 			})
 		}
@@ -230,14 +243,14 @@ type goTermNode struct {
 	rdf.Term
 	depth int
 	wid   int
-	bits  []*big.Int
+	bits  []smear.GeneSet
 }
 
 func (n *goTermNode) DOTID() string { return n.Term.Value }
 func (n *goTermNode) Attributes() []encoding.Attribute {
 	bits := make([]string, len(n.bits))
 	for i, b := range n.bits {
-		bits[i] = fmt.Sprintf("%d:%0*b", i, n.wid, b)
+		bits[i] = fmt.Sprintf("%d:%s", i, b.Binary(n.wid))
 	}
 	return []encoding.Attribute{
 		{Key: "label", Value: fmt.Sprintf("GO:%s [%d]\n%s", strip(n.Value, "<obo:GO_", ">"), n.depth, strings.Join(bits, "\n"))},
@@ -0,0 +1,313 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+// permutationSeed is the seed for differential test
+// permutations so that results are reproducible.
+const permutationSeed = 1
+
+// design is the experimental design for a set of samples.
+type design struct {
+	// group holds the group of each sample.
+	group map[string]string
+
+	// strata holds the covariate values of each
+	// sample joined into a single stratum label.
+	strata map[string]string
+}
+
+// readDesign returns the experimental design held in the tab-delimited
+// file at path. The first row is a header, the first column holds sample
+// names, the second holds the group of each sample and any remaining
+// columns hold categorical covariates.
+func readDesign(path string) (*design, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		r, err = gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := csv.NewReader(r)
+	c.Comma = '\t'
+	c.Comment = '#'
+	labels, err := c.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if len(labels) < 2 {
+		return nil, fmt.Errorf("design header must have sample and group columns: %q", labels)
+	}
+
+	d := &design{group: make(map[string]string), strata: make(map[string]string)}
+	for {
+		rec, err := c.Read()
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		sample := rec[0]
+		if _, ok := d.group[sample]; ok {
+			return nil, fmt.Errorf("duplicate sample in design: %q", sample)
+		}
+		d.group[sample] = rec[1]
+		d.strata[sample] = strings.Join(rec[2:], "\t")
+	}
+	return d, nil
+}
+
+// differential performs differential tests of GO term counts between two
+// groups of samples. A nil *differential is a no-op.
+type differential struct {
+	// groups is the pair of groups being
+	// compared. Fold changes are groups[0]
+	// relative to groups[1].
+	groups [2]string
+
+	// samples holds the indexes into the
+	// count data of the tested samples and
+	// isFirst whether each is in groups[0].
+	samples []int
+	isFirst []bool
+
+	// libSize holds the total count for
+	// each sample in the count data.
+	libSize []float64
+
+	// perms holds the permuted group labels
+	// used for the null distribution.
+	perms [][]bool
+}
+
+// newDifferential returns a differential test for the samples in data
+// grouped by d. The contrast is a comma-separated pair of group names; if
+// it is empty, d must have exactly two groups and they are compared in
+// sorted order. Group labels are permuted n times within the strata
+// defined by the covariates of d to obtain the null distribution.
+func newDifferential(d *design, data *smear.CountData, contrast string, n int) (*differential, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid number of permutations: %d", n)
+	}
+	var groups [2]string
+	if contrast == "" {
+		seen := make(map[string]bool)
+		var names []string
+		for _, s := range data.Names {
+			g, ok := d.group[s]
+			if ok && !seen[g] {
+				seen[g] = true
+				names = append(names, g)
+			}
+		}
+		if len(names) != 2 {
+			return nil, fmt.Errorf("design has %d groups for the counted samples: use -contrast to select two", len(names))
+		}
+		sort.Strings(names)
+		copy(groups[:], names)
+	} else {
+		names := strings.Split(contrast, ",")
+		if len(names) != 2 {
+			return nil, fmt.Errorf("invalid contrast %q: must be a pair of group names", contrast)
+		}
+		copy(groups[:], names)
+	}
+
+	diff := &differential{groups: groups, libSize: make([]float64, len(data.Names))}
+	var count [2]int
+	strata := make(map[string][]int)
+	for i, s := range data.Names {
+		g, ok := d.group[s]
+		if !ok || (g != groups[0] && g != groups[1]) {
+			continue
+		}
+		strata[d.strata[s]] = append(strata[d.strata[s]], len(diff.samples))
+		diff.samples = append(diff.samples, i)
+		diff.isFirst = append(diff.isFirst, g == groups[0])
+		if g == groups[0] {
+			count[0]++
+		} else {
+			count[1]++
+		}
+	}
+	for i, c := range count {
+		if c == 0 {
+			return nil, fmt.Errorf("no counted samples in group %q", groups[i])
+		}
+	}
+	for _, counts := range data.Counts {
+		for i, v := range counts {
+			diff.libSize[i] += v
+		}
+	}
+
+	// Permute labels within each stratum.
+	keys := make([]string, 0, len(strata))
+	for k := range strata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rnd := rand.New(rand.NewSource(permutationSeed))
+	diff.perms = make([][]bool, n)
+	for i := range diff.perms {
+		p := make([]bool, len(diff.isFirst))
+		for _, k := range keys {
+			idx := strata[k]
+			labels := make([]bool, len(idx))
+			for j, s := range idx {
+				labels[j] = diff.isFirst[s]
+			}
+			rnd.Shuffle(len(labels), func(a, b int) { labels[a], labels[b] = labels[b], labels[a] })
+			for j, s := range idx {
+				p[s] = labels[j]
+			}
+		}
+		diff.perms[i] = p
+	}
+	return diff, nil
+}
+
+// termTest is the result of a differential test for a GO term.
+type termTest struct {
+	term         string
+	log2FC       float64
+	meanCPM      [2]float64
+	pValue, qVal float64
+}
+
+// test returns the differential test results for each term in the term ×
+// sample matrix m. The fold change is the difference in mean log2 counts
+// per million between the groups, and the p-value is the two-sided
+// permutation p-value for the fold change.
+func (d *differential) test(terms []string, m *smear.Matrix) []termTest {
+	y := make([]float64, len(d.samples))
+	results := make([]termTest, len(terms))
+	for i, term := range terms {
+		r := termTest{term: term}
+		var n [2]float64
+		for j, s := range d.samples {
+			var cpm float64
+			if d.libSize[s] != 0 {
+				cpm = m.At(i, s) / d.libSize[s] * 1e6
+			}
+			y[j] = math.Log2(cpm + 1)
+			g := 1
+			if d.isFirst[j] {
+				g = 0
+			}
+			r.meanCPM[g] += cpm
+			n[g]++
+		}
+		r.meanCPM[0] /= n[0]
+		r.meanCPM[1] /= n[1]
+
+		r.log2FC = meanDiff(y, d.isFirst)
+		obs := math.Abs(r.log2FC)
+		var b int
+		for _, p := range d.perms {
+			// Allow for floating point error in
+			// permutations equivalent to the data.
+			if math.Abs(meanDiff(y, p)) >= obs-1e-12 {
+				b++
+			}
+		}
+		r.pValue = float64(b+1) / float64(len(d.perms)+1)
+		results[i] = r
+	}
+
+	p := make([]float64, len(results))
+	for i, r := range results {
+		p[i] = r.pValue
+	}
+	for i, q := range benjaminiHochberg(p) {
+		results[i].qVal = q
+	}
+	return results
+}
+
+// meanDiff returns the difference between the mean of the y values in
+// the first group and the mean of those in the second.
+func meanDiff(y []float64, isFirst []bool) float64 {
+	var sum, n [2]float64
+	for i, v := range y {
+		g := 1
+		if isFirst[i] {
+			g = 0
+		}
+		sum[g] += v
+		n[g]++
+	}
+	return sum[0]/n[0] - sum[1]/n[1]
+}
+
+// benjaminiHochberg returns the Benjamini-Hochberg adjusted q-values for
+// the p-values in p.
+func benjaminiHochberg(p []float64) []float64 {
+	idx := make([]int, len(p))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return p[idx[i]] < p[idx[j]] })
+
+	q := make([]float64, len(p))
+	least := 1.0
+	for k := len(idx) - 1; k >= 0; k-- {
+		v := p[idx[k]] * float64(len(p)) / float64(k+1)
+		if v < least {
+			least = v
+		}
+		q[idx[k]] = least
+	}
+	return q
+}
+
+// write performs the differential tests for the term × sample matrix m
+// and writes the results to the differential directory in dir.
+func (d *differential) write(dir, path string, terms []string, m *smear.Matrix) error {
+	if d == nil {
+		return nil
+	}
+	results := d.test(terms, m)
+
+	return writeFile(filepath.Join(dir, "differential", path+".tsv"), false, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "term\tlog2FC\tmeanCPM_%s\tmeanCPM_%s\tp\tq\n", d.groups[0], d.groups[1])
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			_, err = fmt.Fprintf(w, "%s\t%v\t%v\t%v\t%v\t%v\n", r.term, r.log2FC, r.meanCPM[0], r.meanCPM[1], r.pValue, r.qVal)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
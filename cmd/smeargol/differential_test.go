@@ -0,0 +1,323 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+// Reference q-values were calculated by hand.
+var benjaminiHochbergTests = []struct {
+	p    []float64
+	want []float64
+}{
+	{
+		p:    nil,
+		want: []float64{},
+	},
+	{
+		p:    []float64{0.5},
+		want: []float64{0.5},
+	},
+	{
+		p:    []float64{0.01, 0.02, 0.03, 0.04, 0.05},
+		want: []float64{0.05, 0.05, 0.05, 0.05, 0.05},
+	},
+	{
+		p:    []float64{0.04, 0.001, 0.03, 0.9, 0.01},
+		want: []float64{0.05, 0.005, 0.05, 0.9, 0.025},
+	},
+	{
+		p:    []float64{0.01, 0.01, 0.5, 0.2},
+		want: []float64{0.02, 0.02, 0.5, 0.2666666666666667},
+	},
+	{
+		p:    []float64{1, 1, 1},
+		want: []float64{1, 1, 1},
+	},
+}
+
+func TestBenjaminiHochberg(t *testing.T) {
+	for _, test := range benjaminiHochbergTests {
+		got := benjaminiHochberg(test.p)
+		if len(got) != len(test.want) {
+			t.Errorf("unexpected number of q-values for %v: got:%d want:%d", test.p, len(got), len(test.want))
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-test.want[i]) > 1e-12 {
+				t.Errorf("unexpected q-values for %v: got:%v want:%v", test.p, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+var meanDiffTests = []struct {
+	y       []float64
+	isFirst []bool
+	want    float64
+}{
+	{y: []float64{1, 2, 3, 4}, isFirst: []bool{true, true, false, false}, want: -2},
+	{y: []float64{1, 2, 3, 4}, isFirst: []bool{false, false, true, true}, want: 2},
+	{y: []float64{1, 5, 3, 3}, isFirst: []bool{true, true, false, false}, want: 0},
+	{y: []float64{6, 1, 2, 3}, isFirst: []bool{true, false, false, false}, want: 4},
+}
+
+func TestMeanDiff(t *testing.T) {
+	for _, test := range meanDiffTests {
+		got := meanDiff(test.y, test.isFirst)
+		if got != test.want {
+			t.Errorf("unexpected mean difference for %v %v: got:%v want:%v", test.y, test.isFirst, got, test.want)
+		}
+	}
+}
+
+// testDifferentialData returns count data for a single gene in three
+// samples named A1-A3 and three named B1-B3.
+func testDifferentialData(t *testing.T) *smear.CountData {
+	names := []string{"A1", "A2", "A3", "B1", "B2", "B3"}
+	counts := map[string][]float64{
+		"ENSG00000000001": {1e6, 1e6, 1e6, 1e6, 1e6, 1e6},
+	}
+	data, err := smear.NewCountData(names, []string{"ENSG00000000001"}, counts)
+	if err != nil {
+		t.Fatalf("unexpected error creating count data: %v", err)
+	}
+	return data
+}
+
+var newDifferentialTests = []struct {
+	name        string
+	design      string
+	contrast    string
+	n           int
+	wantGroups  [2]string
+	wantSamples []int
+	wantFirst   []bool
+	wantErr     bool
+}{
+	{
+		name:        "two groups",
+		design:      "sample\tgroup\nA1\ta\nA2\ta\nA3\ta\nB1\tb\nB2\tb\nB3\tb\n",
+		n:           10,
+		wantGroups:  [2]string{"a", "b"},
+		wantSamples: []int{0, 1, 2, 3, 4, 5},
+		wantFirst:   []bool{true, true, true, false, false, false},
+	},
+	{
+		name:        "contrast",
+		design:      "sample\tgroup\nA1\ta\nA2\ta\nA3\tc\nB1\tb\nB2\tb\nB3\tc\n",
+		contrast:    "c,a",
+		n:           10,
+		wantGroups:  [2]string{"c", "a"},
+		wantSamples: []int{0, 1, 2, 5},
+		wantFirst:   []bool{false, false, true, true},
+	},
+	{
+		name:    "three groups",
+		design:  "sample\tgroup\nA1\ta\nA2\ta\nA3\tc\nB1\tb\nB2\tb\nB3\tc\n",
+		n:       10,
+		wantErr: true,
+	},
+	{
+		name:     "empty group",
+		design:   "sample\tgroup\nA1\ta\nA2\ta\nA3\ta\nB1\tb\nB2\tb\nB3\tb\n",
+		contrast: "a,c",
+		n:        10,
+		wantErr:  true,
+	},
+	{
+		name:     "invalid contrast",
+		design:   "sample\tgroup\nA1\ta\nA2\ta\nA3\ta\nB1\tb\nB2\tb\nB3\tb\n",
+		contrast: "a",
+		n:        10,
+		wantErr:  true,
+	},
+	{
+		name:    "no permutations",
+		design:  "sample\tgroup\nA1\ta\nA2\ta\nA3\ta\nB1\tb\nB2\tb\nB3\tb\n",
+		n:       0,
+		wantErr: true,
+	},
+}
+
+func TestNewDifferential(t *testing.T) {
+	data := testDifferentialData(t)
+	for _, test := range newDifferentialTests {
+		d := testDesign(t, test.design)
+		got, err := newDifferential(d, data, test.contrast, test.n)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %q: got:%v want error:%t", test.name, err, test.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if got.groups != test.wantGroups {
+			t.Errorf("unexpected groups for %q: got:%q want:%q", test.name, got.groups, test.wantGroups)
+		}
+		if !reflect.DeepEqual(got.samples, test.wantSamples) {
+			t.Errorf("unexpected samples for %q: got:%v want:%v", test.name, got.samples, test.wantSamples)
+		}
+		if !reflect.DeepEqual(got.isFirst, test.wantFirst) {
+			t.Errorf("unexpected group membership for %q: got:%v want:%v", test.name, got.isFirst, test.wantFirst)
+		}
+		if len(got.perms) != test.n {
+			t.Errorf("unexpected number of permutations for %q: got:%d want:%d", test.name, len(got.perms), test.n)
+		}
+	}
+}
+
+func TestPermutationStrata(t *testing.T) {
+	// Each stratum holds one sample from each group, so
+	// permutations within strata must keep one sample of
+	// each group in each stratum.
+	const design = "sample\tgroup\tbatch\nA1\ta\t1\nA2\ta\t2\nA3\ta\t3\nB1\tb\t1\nB2\tb\t2\nB3\tb\t3\n"
+	data := testDifferentialData(t)
+	d, err := newDifferential(testDesign(t, design), data, "", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	strata := [][2]int{{0, 3}, {1, 4}, {2, 5}}
+	for i, p := range d.perms {
+		for _, s := range strata {
+			if p[s[0]] == p[s[1]] {
+				t.Errorf("permutation %d does not preserve stratum %v: %v", i, s, p)
+			}
+		}
+	}
+}
+
+const testDifferentialOBO = `format-version: 1.4
+
+[Term]
+id: GO:0008150
+name: biological_process
+namespace: biological_process
+
+[Term]
+id: GO:0000001
+name: process one
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+
+[Term]
+id: GO:0000002
+name: process two
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+
+[Term]
+id: GO:0000003
+name: process three
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+`
+
+func TestDifferentialTest(t *testing.T) {
+	// Genes 1 and 2 are painted onto terms 1 and 2, and term 3
+	// has no genes. Gene 3 is unannotated and brings the library
+	// size of each sample to one million so that CPM values equal
+	// the term counts.
+	names := []string{"A1", "A2", "A3", "B1", "B2", "B3"}
+	genes := []string{"ENSG00000000001", "ENSG00000000002", "ENSG00000000003"}
+	counts := map[string][]float64{
+		"ENSG00000000001": {7, 7, 7, 1, 1, 1},
+		"ENSG00000000002": {1, 2, 3, 1, 2, 3},
+		"ENSG00000000003": make([]float64, len(names)),
+	}
+	for i := range names {
+		counts["ENSG00000000003"][i] = 1e6 - counts["ENSG00000000001"][i] - counts["ENSG00000000002"][i]
+	}
+	data, err := smear.NewCountData(names, genes, counts)
+	if err != nil {
+		t.Fatalf("unexpected error creating count data: %v", err)
+	}
+	const annotations = `<obo:GO_0000001> <local:annotates> <ensembl:ENSG00000000001> .
+<obo:GO_0000002> <local:annotates> <ensembl:ENSG00000000002> .
+`
+	terms := []string{"<obo:GO_0000001>", "<obo:GO_0000002>", "<obo:GO_0000003>"}
+	m := testTermMatrix(t, testDifferentialOBO, annotations, data, terms)
+
+	// There are 20 ways to choose three of six samples, and
+	// only two of these separate the groups as completely as
+	// the observed labels, so the exact two-sided p-value for
+	// a complete separation is 2/20.
+	const design = "sample\tgroup\nA1\ta\nA2\ta\nA3\ta\nB1\tb\nB2\tb\nB3\tb\n"
+	d, err := newDifferential(testDesign(t, design), data, "", 20000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := d.test(terms, m)
+
+	wantFC := []float64{2, 0, 0}
+	wantCPM := [][2]float64{{7, 1}, {2, 2}, {0, 0}}
+	wantP := []float64{0.1, 1, 1}
+	for i, r := range got {
+		if math.Abs(r.log2FC-wantFC[i]) > 1e-9 {
+			t.Errorf("unexpected log2 fold change for %s: got:%v want:%v", r.term, r.log2FC, wantFC[i])
+		}
+		for g := range r.meanCPM {
+			if math.Abs(r.meanCPM[g]-wantCPM[i][g]) > 1e-9 {
+				t.Errorf("unexpected mean CPM for %s: got:%v want:%v", r.term, r.meanCPM, wantCPM[i])
+				break
+			}
+		}
+		if math.Abs(r.pValue-wantP[i]) > 0.01 {
+			t.Errorf("unexpected p-value for %s: got:%v want:%v", r.term, r.pValue, wantP[i])
+		}
+	}
+	wantQ := benjaminiHochberg([]float64{got[0].pValue, got[1].pValue, got[2].pValue})
+	for i, r := range got {
+		if r.qVal != wantQ[i] {
+			t.Errorf("unexpected q-value for %s: got:%v want:%v", r.term, r.qVal, wantQ[i])
+		}
+	}
+}
+
+// testTermMatrix returns the term × sample matrix for the given terms
+// of data painted onto the ontology in obo with the given annotations.
+func testTermMatrix(t *testing.T, obo, annotations string, data *smear.CountData, terms []string) *smear.Matrix {
+	ctx := context.Background()
+	rels, err := smear.ParseRelations("")
+	if err != nil {
+		t.Fatalf("unexpected error parsing relations: %v", err)
+	}
+	o, err := smear.LoadOntology(ctx, strings.NewReader(obo), true, rels)
+	if err != nil {
+		t.Fatalf("unexpected error loading ontology: %v", err)
+	}
+	err = smear.Annotate(ctx, o, strings.NewReader(annotations), data, smear.EvidenceFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error annotating ontology: %v", err)
+	}
+	p, err := smear.Distribute(ctx, o, data, 1)
+	if err != nil {
+		t.Fatalf("unexpected error distributing counts: %v", err)
+	}
+	return p.TermMatrix(0, terms)
+}
+
+// testDesign returns the design held in the tab-delimited text.
+func testDesign(t *testing.T, text string) *design {
+	path := filepath.Join(t.TempDir(), "design.tsv")
+	err := os.WriteFile(path, []byte(text), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := readDesign(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading design: %v", err)
+	}
+	return d
+}
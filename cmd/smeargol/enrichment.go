@@ -0,0 +1,272 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+// Analysis modes.
+const (
+	modeSmear  = "smear"
+	modeEnrich = "enrich"
+)
+
+// readGeneList returns the set of gene identifiers held one per line in
+// the file at path as a set indexed by the genes of data. If strip is
+// true, version suffixes are removed from the identifiers. Identifiers
+// that are not in data are counted in missing.
+func readGeneList(path string, data *smear.CountData, strip bool) (genes *smear.GeneSet, missing int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		r, err = gzip.NewReader(f)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var idx []int
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		id := strings.TrimSpace(sc.Text())
+		if id == "" || strings.HasPrefix(id, "#") {
+			continue
+		}
+		if strip {
+			id = smear.StripVersion(id)
+		}
+		i, ok := data.Index(id)
+		if !ok {
+			missing++
+			continue
+		}
+		idx = append(idx, i)
+	}
+	err = sc.Err()
+	if err != nil {
+		return nil, missing, err
+	}
+	set := smear.NewGeneSet(idx...)
+	return &set, missing, nil
+}
+
+// countsAtLeast returns the set of genes in data with a count of at least
+// least in the given sample as a set indexed by the genes of data.
+func countsAtLeast(data *smear.CountData, sample int, least float64) smear.GeneSet {
+	var genes []int
+	for i, id := range data.GeneIDs {
+		if data.Counts[id][sample] >= least {
+			genes = append(genes, i)
+		}
+	}
+	return smear.NewGeneSet(genes...)
+}
+
+// termEnrichment is the result of an over-representation test for a GO
+// term.
+type termEnrichment struct {
+	term  string
+	depth int
+
+	// k is the number of foreground genes
+	// painted onto the term, n is the number
+	// of universe genes painted onto the term,
+	// K is the number of foreground genes in
+	// the universe and N is the size of the
+	// universe.
+	k, n, K, N int
+
+	pValue, qVal float64
+}
+
+// enrichment performs over-representation tests of the foreground genes for
+// the terms in levels, the levels of the ontology below root. The genesOf
+// function returns the genes painted onto a term, or false if the term was
+// not painted, and the universe is the set of genes painted onto root. Only
+// terms at the given depth are tested unless depth is negative, in which
+// case all terms are tested.
+func enrichment(root rdf.Term, levels []smear.Level, foreground smear.GeneSet, genesOf func(term string) (smear.GeneSet, bool), depth int) []termEnrichment {
+	universe, ok := genesOf(root.Value)
+	if !ok {
+		return nil
+	}
+	fg := foreground.Intersect(universe)
+	N := universe.Len()
+	K := fg.Len()
+
+	var results []termEnrichment
+	for _, l := range levels {
+		if depth >= 0 && l.Depth != depth {
+			continue
+		}
+		for _, t := range l.Terms {
+			set, ok := genesOf(t)
+			if !ok {
+				continue
+			}
+			n := set.Len()
+			if n == 0 {
+				continue
+			}
+			k := set.IntersectionLen(fg)
+			results = append(results, termEnrichment{
+				term:   strip(t, "<obo:", ">"),
+				depth:  l.Depth,
+				k:      k,
+				n:      n,
+				K:      K,
+				N:      N,
+				pValue: hypergeometricUpper(k, n, K, N),
+			})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].depth != results[j].depth {
+			return results[i].depth < results[j].depth
+		}
+		return results[i].term < results[j].term
+	})
+
+	p := make([]float64, len(results))
+	for i, r := range results {
+		p[i] = r.pValue
+	}
+	for i, q := range benjaminiHochberg(p) {
+		results[i].qVal = q
+	}
+	return results
+}
+
+// sampleGenes returns a function that returns the genes painted onto a term
+// in the given sample for the aspect with index root.
+func sampleGenes(p *smear.Painting, root, sample int) func(term string) (smear.GeneSet, bool) {
+	return func(term string) (smear.GeneSet, bool) {
+		return p.Genes(root, term, sample)
+	}
+}
+
+// anySampleGenes returns a function that returns the genes painted onto a
+// term in any sample for the aspect with index root.
+func anySampleGenes(p *smear.Painting, root, samples int) func(term string) (smear.GeneSet, bool) {
+	return func(term string) (smear.GeneSet, bool) {
+		var set smear.GeneSet
+		for sample := 0; sample < samples; sample++ {
+			genes, ok := p.Genes(root, term, sample)
+			if !ok {
+				return smear.GeneSet{}, false
+			}
+			set = set.Union(genes)
+		}
+		return set, true
+	}
+}
+
+// hypergeometricUpper returns the probability of drawing at least k
+// successes in n draws without replacement from a population of size N
+// holding K successes. This is the one-sided Fisher's exact test p-value
+// for over-representation.
+func hypergeometricUpper(k, n, K, N int) float64 {
+	if k <= 0 {
+		return 1
+	}
+	hi := n
+	if K < hi {
+		hi = K
+	}
+	total := logChoose(N, n)
+	var p float64
+	for i := k; i <= hi; i++ {
+		p += math.Exp(logChoose(K, i) + logChoose(N-K, n-i) - total)
+	}
+	if p > 1 {
+		p = 1
+	}
+	return p
+}
+
+// logChoose returns the natural logarithm of the binomial coefficient
+// n choose k.
+func logChoose(n, k int) float64 {
+	if k < 0 || k > n {
+		return math.Inf(-1)
+	}
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// writeEnrichment writes the enrichment results to the enrichment directory
+// in dir with the given name.
+func writeEnrichment(dir, name string, results []termEnrichment) error {
+	return writeFile(filepath.Join(dir, "enrichment", name+".tsv"), false, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, "term\tdepth\tk\tn\tK\tN\tfold\tp\tq")
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			fold := (float64(r.k) / float64(r.n)) / (float64(r.K) / float64(r.N))
+			if r.K == 0 {
+				fold = 0
+			}
+			_, err = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%v\t%v\t%v\n", r.term, r.depth, r.k, r.n, r.K, r.N, fold, r.pValue, r.qVal)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// enrich performs over-representation tests for each of the roots and
+// writes the results to the enrichment directory in dir. If foreground is
+// not nil, it is tested against the universe of genes painted in any
+// sample. Otherwise, for each sample, the genes with a count of at least
+// least are tested against the universe of genes painted in that sample.
+// If ctx is cancelled, testing stops and the context's error is returned.
+func enrich(ctx context.Context, dir string, g *smear.Ontology, p *smear.Painting, data *smear.CountData, foreground *smear.GeneSet, least float64, depth int) error {
+	for k, root := range p.Roots() {
+		rootName := strip(root.Value, "<obo:", ">")
+		levels, err := smear.Levels(ctx, g, root)
+		if err != nil {
+			return err
+		}
+		if foreground != nil {
+			results := enrichment(root, levels, *foreground, anySampleGenes(p, k, len(data.Names)), depth)
+			err = writeEnrichment(dir, rootName, results)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		for sample, name := range data.Names {
+			results := enrichment(root, levels, countsAtLeast(data, sample, least), sampleGenes(p, k, sample), depth)
+			err = writeEnrichment(dir, name+"_"+rootName, results)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+var hypergeometricUpperTests = []struct {
+	k, n, K, N int
+	want       float64
+}{
+	{k: 0, n: 5, K: 5, N: 10, want: 1},
+	{k: 1, n: 1, K: 1, N: 2, want: 0.5},
+	// Fisher's lady tasting tea.
+	{k: 4, n: 4, K: 4, N: 8, want: 1.0 / 70},
+	{k: 3, n: 4, K: 4, N: 8, want: 17.0 / 70},
+	{k: 3, n: 5, K: 5, N: 10, want: 126.0 / 252},
+	{k: 5, n: 10, K: 5, N: 20, want: 3003.0 / 184756},
+	{k: 1, n: 3, K: 0, N: 10, want: 0},
+	{k: 3, n: 2, K: 5, N: 10, want: 0},
+	{k: 2, n: 10, K: 2, N: 10, want: 1},
+}
+
+func TestHypergeometricUpper(t *testing.T) {
+	for _, test := range hypergeometricUpperTests {
+		got := hypergeometricUpper(test.k, test.n, test.K, test.N)
+		if math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("unexpected p-value for k=%d n=%d K=%d N=%d: got:%v want:%v", test.k, test.n, test.K, test.N, got, test.want)
+		}
+	}
+}
+
+var logChooseTests = []struct {
+	n, k int
+	want float64
+}{
+	{n: 0, k: 0, want: 0},
+	{n: 5, k: 0, want: 0},
+	{n: 5, k: 5, want: 0},
+	{n: 5, k: 2, want: math.Log(10)},
+	{n: 20, k: 10, want: math.Log(184756)},
+	{n: 3, k: 4, want: math.Inf(-1)},
+	{n: 3, k: -1, want: math.Inf(-1)},
+}
+
+func TestLogChoose(t *testing.T) {
+	for _, test := range logChooseTests {
+		got := logChoose(test.n, test.k)
+		if got != test.want && math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("unexpected result for %d choose %d: got:%v want:%v", test.n, test.k, got, test.want)
+		}
+	}
+}
+
+// testEnrichmentGenes is the set of genes painted onto each term of a
+// small ontology with the root GO_0000000. GO_0000004 is reached but not
+// painted and GO_0000005 is painted with no genes.
+var testEnrichmentGenes = map[string]smear.GeneSet{
+	"<obo:GO_0000000>": smear.NewGeneSet(0, 1, 2, 3, 4, 5, 6, 7),
+	"<obo:GO_0000001>": smear.NewGeneSet(0, 1, 2, 3),
+	"<obo:GO_0000002>": smear.NewGeneSet(4, 5, 6, 7),
+	"<obo:GO_0000003>": smear.NewGeneSet(0, 1),
+	"<obo:GO_0000005>": smear.NewGeneSet(),
+}
+
+var testEnrichmentLevels = []smear.Level{
+	{Depth: 0, Terms: []string{"<obo:GO_0000000>"}},
+	{Depth: 1, Terms: []string{"<obo:GO_0000002>", "<obo:GO_0000001>"}},
+	{Depth: 2, Terms: []string{"<obo:GO_0000003>", "<obo:GO_0000004>", "<obo:GO_0000005>"}},
+}
+
+var enrichmentTests = []struct {
+	name       string
+	foreground smear.GeneSet
+	depth      int
+	want       []termEnrichment
+}{
+	{
+		name:       "all depths",
+		foreground: smear.NewGeneSet(0, 1, 2, 3, 8),
+		depth:      -1,
+		want: []termEnrichment{
+			{term: "GO_0000000", depth: 0, k: 4, n: 8, K: 4, N: 8, pValue: 1, qVal: 1},
+			{term: "GO_0000001", depth: 1, k: 4, n: 4, K: 4, N: 8, pValue: 1.0 / 70, qVal: 4.0 / 70},
+			{term: "GO_0000002", depth: 1, k: 0, n: 4, K: 4, N: 8, pValue: 1, qVal: 1},
+			{term: "GO_0000003", depth: 2, k: 2, n: 2, K: 4, N: 8, pValue: 6.0 / 28, qVal: 3.0 / 7},
+		},
+	},
+	{
+		name:       "single depth",
+		foreground: smear.NewGeneSet(0, 1, 2, 3),
+		depth:      1,
+		want: []termEnrichment{
+			{term: "GO_0000001", depth: 1, k: 4, n: 4, K: 4, N: 8, pValue: 1.0 / 70, qVal: 2.0 / 70},
+			{term: "GO_0000002", depth: 1, k: 0, n: 4, K: 4, N: 8, pValue: 1, qVal: 1},
+		},
+	},
+	{
+		name:       "empty foreground",
+		foreground: smear.GeneSet{},
+		depth:      2,
+		want: []termEnrichment{
+			{term: "GO_0000003", depth: 2, k: 0, n: 2, K: 0, N: 8, pValue: 1, qVal: 1},
+		},
+	},
+}
+
+func TestEnrichment(t *testing.T) {
+	genesOf := func(term string) (smear.GeneSet, bool) {
+		set, ok := testEnrichmentGenes[term]
+		return set, ok
+	}
+	root := rdf.Term{Value: "<obo:GO_0000000>"}
+	for _, test := range enrichmentTests {
+		got := enrichment(root, testEnrichmentLevels, test.foreground, genesOf, test.depth)
+		if len(got) != len(test.want) {
+			t.Errorf("unexpected number of results for %q: got:%d want:%d", test.name, len(got), len(test.want))
+			continue
+		}
+		for i, r := range got {
+			w := test.want[i]
+			if r.term != w.term || r.depth != w.depth || r.k != w.k || r.n != w.n || r.K != w.K || r.N != w.N {
+				t.Errorf("unexpected result %d for %q: got:%+v want:%+v", i, test.name, r, w)
+			}
+			if math.Abs(r.pValue-w.pValue) > 1e-12 || math.Abs(r.qVal-w.qVal) > 1e-12 {
+				t.Errorf("unexpected p or q-value for %s in %q: got:%v,%v want:%v,%v", r.term, test.name, r.pValue, r.qVal, w.pValue, w.qVal)
+			}
+		}
+	}
+
+	unpainted := rdf.Term{Value: "<obo:GO_0000004>"}
+	if got := enrichment(unpainted, testEnrichmentLevels, smear.NewGeneSet(0), genesOf, -1); got != nil {
+		t.Errorf("unexpected results for unpainted root: %+v", got)
+	}
+}
+
+func TestCountsAtLeast(t *testing.T) {
+	data, err := smear.NewCountData(
+		[]string{"S1", "S2"},
+		[]string{"ENSG00000000001", "ENSG00000000002", "ENSG00000000003"},
+		map[string][]float64{
+			"ENSG00000000001": {0, 5},
+			"ENSG00000000002": {2, 1},
+			"ENSG00000000003": {10, 0},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error creating count data: %v", err)
+	}
+	for _, test := range []struct {
+		sample int
+		least  float64
+		want   []int
+	}{
+		{sample: 0, least: 1, want: []int{1, 2}},
+		{sample: 0, least: 2, want: []int{1, 2}},
+		{sample: 0, least: 3, want: []int{2}},
+		{sample: 1, least: 1, want: []int{0, 1}},
+		{sample: 1, least: 6, want: nil},
+		{sample: 1, least: 0, want: []int{0, 1, 2}},
+	} {
+		got := countsAtLeast(data, test.sample, test.least).Genes()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected genes for sample %d with at least %v: got:%v want:%v", test.sample, test.least, got, test.want)
+		}
+	}
+}
+
+func TestReadGeneList(t *testing.T) {
+	data, err := smear.NewCountData(
+		[]string{"S1"},
+		[]string{"ENSG00000000001", "ENSG00000000002", "ENSG00000000003"},
+		map[string][]float64{
+			"ENSG00000000001": {1},
+			"ENSG00000000002": {1},
+			"ENSG00000000003": {1},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error creating count data: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "foreground.txt")
+	err = os.WriteFile(path, []byte("# foreground\nENSG00000000003.2\n\nENSG00000000001\n  ENSG00000000009  \n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		strip       bool
+		want        []int
+		wantMissing int
+	}{
+		{strip: false, want: []int{0}, wantMissing: 2},
+		{strip: true, want: []int{0, 2}, wantMissing: 1},
+	} {
+		genes, missing, err := readGeneList(path, data, test.strip)
+		if err != nil {
+			t.Fatalf("unexpected error reading gene list: %v", err)
+		}
+		if got := genes.Genes(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected genes with strip=%t: got:%v want:%v", test.strip, got, test.want)
+		}
+		if missing != test.wantMissing {
+			t.Errorf("unexpected number of missing genes with strip=%t: got:%d want:%d", test.strip, missing, test.wantMissing)
+		}
+	}
+}
@@ -13,20 +13,144 @@
 // The first row is expected to be labelled with the first column being Geneid
 // and the remaining columns holding the names of the samples.
 //
-// The Gene Ontology is required to be in Owl format. The file can be
-// obtained from http://current.geneontology.org/ontology/go.owl.
+// Counts may also be provided as featureCounts output, where the Chr, Start,
+// End, Strand and Length columns following Geneid are ignored, or as a
+// directory of sample directories each holding a salmon quant.sf or kallisto
+// abundance.tsv file, optionally gzip compressed. Sample quantifications are
+// named by their directory and are merged, with features missing from a
+// sample given a zero count. The format is determined from the file headers
+// unless specified by the -format flag. The -quant flag selects whether the
+// NumReads or est_counts columns (counts), or the TPM columns (tpm) are used
+// for salmon and kallisto input.
+//
+// Versioned Ensembl identifiers (ENSG00000000000.1) may be used if the
+// -strip-version flag is set, in which case version suffixes are removed
+// before identifiers are matched. Transcript level counts are summed into
+// gene level counts before smearing when a transcript to gene mapping is
+// provided by the -tx2gene flag. The mapping may be a tab-delimited table
+// with transcript identifiers in the first column and gene identifiers in
+// the second, or RDF N-Triples in the form:
+//
+//  <transcript:ENST00000000000> <obo:SO_transcribed_from> <ensembl:ENSG00000000000> .
+//
+// The Gene Ontology is required to be in Owl or OBO 1.4 format. The files
+// can be obtained from http://current.geneontology.org/ontology/go.owl or
+// http://current.geneontology.org/ontology/go-basic.obo. The format is
+// determined from the content of the file.
 //
 // The ENSG to GO mapping is expected to be in RDF N-Triples or N-Quads in
 // the form:
 //
 //  <obo:GO_0000000> <local:annotates> <ensembl:ENSG00000000000> .
 //
-// for each GO term to Ensembl gene annotation.
+// for each GO term to Ensembl gene annotation. Alternatively the mapping
+// may be a GAF 2.1 or 2.2 annotation file as provided by the GO Consortium.
+// GAF files are detected by their !gaf-version header and the DB Object ID,
+// DB Object Symbol and DB Object Synonym columns are matched against the
+// gene identifiers in the counts file.
+//
+// Annotations may be filtered by evidence code using the -evidence and
+// -exclude-evidence flags. The lists may hold evidence codes or the group
+// names experimental, high-throughput, phylogenetic, computational,
+// author-statement, curator-statement and electronic. Evidence codes are
+// taken from the GAF Evidence Code column, or for N-Quads from a graph
+// label in the form <evidence:IEA>. Annotations without evidence are
+// excluded when an -evidence list is provided. GAF annotations with a NOT
+// qualifier and N-Quads annotations with a graph label in the form
+// <not:IEA> are never used.
+//
+// Counts are smeared from annotated terms to their ancestors along the
+// is_a relation. Additional relations may be followed by providing them
+// in the -relations flag; the part_of, regulates, negatively_regulates
+// and positively_regulates relations are supported. The has_part relation
+// is never followed since it does not satisfy the GO true path rule.
+// Relations between terms in different GO aspects, such as a biological
+// process that is part_of a cellular component, are not followed since
+// each aspect is analysed separately.
+//
+// The optimal rank of each level matrix is calculated using the singular
+// value threshold selected by the -threshold flag. The unknown-noise
+// threshold is the Gavish and Donoho optimal hard threshold for an unknown
+// noise level, the known-noise threshold is the Gavish and Donoho
+// threshold for the noise level given by -noise, and the marchenko-pastur
+// threshold is the upper edge of the Marchenko-Pastur bulk for the noise
+// level given by -noise, or estimated from the median singular value if
+// -noise is not set. The method, threshold and noise level are recorded in
+// the summary.
+//
+// If -bootstrap is set to a number of replicates, genes are resampled with
+// replacement to form replicates of each level matrix and the mean,
+// standard deviation and 95% percentile interval of the replicate optimal
+// ranks are recorded in the summary.
+//
+// For each level, a term by sample matrix of the counts summed over the
+// genes painted onto each term is also written to the levels
+// subdirectories of the matrices and plots directories, and is summarised
+// in the Levels section of the summary document. These matrices allow
+// samples to be compared at a given level.
+//
+// If a sample design is provided with -design, the term by sample matrices
+// are tested for differences between two groups of samples. The design is
+// a tab-delimited file with a header row, with sample names in the first
+// column, groups in the second and any categorical covariates in the
+// remaining columns. The groups compared are given by -contrast, or are
+// the two design groups in sorted order. For each term, the log2 fold
+// change is the difference in mean log2 counts per million between the
+// groups and the p-value is a two-sided permutation p-value from
+// -permutations permutations of group labels within covariate strata. The
+// fold changes, p-values and Benjamini-Hochberg q-values for each level
+// are written to the differential directory in the output directory.
+//
+// For each sample and root, the level with the highest optimal rank is
+// selected and reported in the Selection section of the summary document.
+// If -select is normalized-rank, levels are instead compared by their
+// optimal rank divided by the smaller of the level matrix dimensions. The
+// -tie-break flag determines whether the shallowest or deepest of equally
+// ranked levels is selected, and the depths of the other equally ranked
+// levels are reported.
+//
+// Singular values are calculated by a full SVD for level matrices with up
+// to -svd-limit elements. Larger matrices have only their -svd-rank largest
+// singular values calculated by randomized truncated SVD, and this is noted
+// in their summaries by SigmaPartial. Since the median singular value of
+// these matrices is not known, the noise level is estimated from the part
+// of the matrix not accounted for by the calculated singular values.
+//
+// Genes are painted onto the DAG and level matrices are analysed by at
+// most -workers concurrent workers, which defaults to the number of
+// available CPUs.
+//
+// Level matrices are written as tab-delimited tables by default. If the
+// -matrix-format flag is mtx, they are instead written in Matrix Market
+// coordinate format with gene and GO term names written to .rows.tsv and
+// .cols.tsv sidecar files. The mtx.gz format writes the same files gzip
+// compressed.
+//
+// If -mode is enrich, the painted DAG is instead used to test GO terms for
+// over-representation of a set of foreground genes and no matrices are
+// written. The foreground is either a list of gene identifiers, one per
+// line, given by -foreground and tested against the universe of genes
+// painted in any sample, or, for each sample, the genes with a count of at
+// least -min-count tested against the genes painted in that sample. Terms
+// at the level given by -depth, or all terms if -depth is negative, are
+// tested using the hypergeometric upper tail (one-sided Fisher's exact
+// test) and Benjamini-Hochberg q-values are calculated for each root.
+// Results are written to the enrichment directory in the output directory.
 //
 // All input files are expected to be gzip compressed and the output is
-// written uncompressed to a matrix and a plot directory. A summary
-// document is written to the specified out file in JSON format corresponding
-// to the following Go structs.
+// written uncompressed to a matrices and a plots directory in the
+// directory specified by -outdir. Existing output is only overwritten if
+// -force is set. A run manifest recording the inputs, flags and completed
+// output is written to manifest.jsonl in the output directory. If -resume
+// is set, a previous run with the same inputs and flags is continued,
+// skipping sample levels that the manifest records as complete. If the run
+// is interrupted, analyses in progress are completed and the summary
+// document is written for the completed work so that the run can be
+// resumed. Output files are written with a .partial suffix that is
+// removed when they are complete. A second interrupt exits immediately,
+// removing any partially written output files. A summary document is
+// written to the specified out file in JSON format corresponding to the
+// following Go structs.
 //
 //  type SummaryDoc struct {
 //  	// Roots is the set of roots in the Gene Ontology.
@@ -35,6 +159,17 @@
 //  	// Summaries contains the summaries of a smeargol
 //  	// analysis.
 //  	Summaries [][]*Summary
+//
+//  	// Levels contains the summaries of the term by
+//  	// sample matrices of each level, for each of the
+//  	// roots. The Rows of these summaries correspond
+//  	// to GO terms and the Cols to samples, and their
+//  	// Name is empty.
+//  	Levels [][]*Summary
+//
+//  	// Selection contains the selected GO level for
+//  	// each sample, for each of the roots.
+//  	Selection [][]*Selection
 //  }
 //
 //  type Summary struct {
@@ -61,36 +196,123 @@
 //  	// user-provided fraction parameters.
 //  	OptimalRank, FractionalRank int
 //
-//  	// Sigma is the complete set of singular values.
+//  	// Threshold is the method used to calculate the
+//  	// singular value threshold for OptimalRank, one of
+//  	// unknown-noise, known-noise or marchenko-pastur.
+//  	// Tau is the threshold value and Noise is the
+//  	// noise level used to calculate it, or estimated
+//  	// from the singular values if not provided.
+//  	Threshold  string
+//  	Tau, Noise float64
+//
+//  	// Bootstrap is the number of bootstrap replicates,
+//  	// formed by resampling genes with replacement, used
+//  	// to estimate the variability of OptimalRank. If it
+//  	// is zero, no bootstrap was performed. RankMean and
+//  	// RankStdDev are the mean and standard deviation of
+//  	// the replicate optimal ranks and RankCI holds their
+//  	// 2.5th and 97.5th percentiles.
+//  	Bootstrap            int
+//  	RankMean, RankStdDev float64
+//  	RankCI               [2]float64
+//
+//  	// Sigma is the set of singular values. It is the
+//  	// complete set unless SigmaPartial is true.
 //  	Sigma []float64
+//
+//  	// SigmaPartial indicates that Sigma holds only the
+//  	// largest singular values, calculated by randomized
+//  	// truncated SVD since the matrix exceeded the size
+//  	// limit for a full SVD. In this case the noise level
+//  	// is estimated from the residual energy of the matrix
+//  	// not accounted for by Sigma, rather than from the
+//  	// median singular value, and FractionalRank is
+//  	// calculated using the sum of the missing singular
+//  	// values implied by that noise level.
+//  	SigmaPartial bool
+//  }
+//
+//  type Selection struct {
+//  	// Name is the name of the sample.
+//  	Name string
+//
+//  	// Root is the root GO term for the selection.
+//  	Root string
+//
+//  	// Depth is the distance from the root of the
+//  	// selected level and Rank is its OptimalRank.
+//  	// NormalizedRank is Rank divided by the smaller
+//  	// of the level matrix's Rows and Cols.
+//  	Depth, Rank    int
+//  	NormalizedRank float64
+//
+//  	// Ties holds the depths of other levels that
+//  	// are equal to the selected level under the
+//  	// selection criterion.
+//  	Ties []int
+//
+//  	// Rule describes the selection criterion and
+//  	// tie-breaking rule used.
+//  	Rule string
 //  }
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
 
-	"gonum.org/v1/gonum/graph/formats/rdf"
-	"gonum.org/v1/gonum/mat"
+	"github.com/kortschak/smeargol/smear"
 )
 
 func main() {
 	var (
-		in       = flag.String("in", "", "specify the counts input (.tsv.gz - required)")
+		mode     = flag.String("mode", "smear", "specify the analysis mode (smear, enrich)")
+		in       = flag.String("in", "", "specify the counts input (.tsv.gz or directory of sample quantifications - required)")
+		format   = flag.String("format", "auto", "specify the counts input format (auto, tsv, featurecounts, salmon, kallisto)")
+		quant    = flag.String("quant", "counts", "specify the quantification measure for salmon and kallisto input (counts, tpm)")
 		out      = flag.String("out", "", "specify the summary output file")
-		ontopath = flag.String("ontology", "", "specify the GO file (.owl.gz - required)")
-		mappath  = flag.String("map", "", "specify the ENSG to GO mapping (.nt.gz/.nq.gz - required)")
+		outdir   = flag.String("outdir", ".", "specify the directory to write matrices and plots to")
+		force    = flag.Bool("force", false, "allow overwriting existing matrices and plots")
+		matfmt   = flag.String("matrix-format", "tsv", "specify the level matrix output format (tsv, mtx, mtx.gz)")
+		resume   = flag.Bool("resume", false, "resume a previous run, skipping completed output")
+		ontopath = flag.String("ontology", "", "specify the GO file (.owl.gz/.obo.gz - required)")
+		mappath  = flag.String("map", "", "specify the ENSG to GO mapping (.nt.gz/.nq.gz/.gaf.gz - required)")
 		lean     = flag.Bool("lean", true, "only load relevant parts of ontology")
+		stripver = flag.Bool("strip-version", false, "remove version suffixes from Ensembl identifiers")
+		txpath   = flag.String("tx2gene", "", "specify the transcript to gene mapping for aggregating transcript counts (.tsv.gz/.nt.gz)")
+		relnames = flag.String("relations", "is_a", "comma separated list of GO relations to smear counts along (is_a, part_of, regulates, negatively_regulates, positively_regulates)")
+		evidence = flag.String("evidence", "", "comma separated list of evidence codes or groups to include (default all)")
+		exclude  = flag.String("exclude-evidence", "", "comma separated list of evidence codes or groups to exclude")
 		cut      = flag.Float64("cut", 1, "minimum valid singular value")
 		frac     = flag.Float64("frac", 0.75, "include singular values up to this cumulative fraction")
+		method   = flag.String("threshold", "unknown-noise", "specify the optimal rank threshold method (unknown-noise, known-noise, marchenko-pastur)")
+		noise    = flag.Float64("noise", 0, "specify the noise level for the known-noise and marchenko-pastur thresholds")
+		boot     = flag.Int("bootstrap", 0, "number of bootstrap replicates for optimal rank intervals (0 for none)")
+		criteria = flag.String("select", "rank", "specify the level selection criterion (rank, normalized-rank)")
+		tiebreak = flag.String("tie-break", "shallowest", "specify the level selection tie-breaking rule (shallowest, deepest)")
+		despath  = flag.String("design", "", "specify the sample design for differential term testing (.tsv/.tsv.gz)")
+		contrast = flag.String("contrast", "", "comma separated pair of design groups to compare (default the two design groups in sorted order)")
+		perms    = flag.Int("permutations", 1000, "number of permutations for differential term testing")
+		fgpath   = flag.String("foreground", "", "specify the foreground gene list for enrich mode")
+		mincount = flag.Float64("min-count", 0, "minimum count for foreground genes for enrich mode when no foreground list is given")
+		depth    = flag.Int("depth", -1, "GO level depth to test in enrich mode (-1 for all levels)")
+		svdlim   = flag.Int("svd-limit", 1e7, "maximum number of matrix elements for a full SVD")
+		svdrank  = flag.Int("svd-rank", 100, "number of singular values to calculate for matrices larger than svd-limit")
+		workers  = flag.Int("workers", runtime.GOMAXPROCS(0), "maximum number of concurrent workers")
 		debug    = flag.Bool("debug", false, "output binary assignments - only small sets")
 		help     = flag.Bool("help", false, "print help text")
 	)
@@ -110,20 +332,144 @@ Ensembl gene ID (ENSG00000000000) and remaining columns being count data.
 The first row is expected to be labelled with the first column being Geneid
 and the remaining columns holding the names of the samples.
 
-The Gene Ontology is required to be in Owl format. The file can be
-obtained from http://current.geneontology.org/ontology/go.owl.
+Counts may also be provided as featureCounts output, where the Chr, Start,
+End, Strand and Length columns following Geneid are ignored, or as a
+directory of sample directories each holding a salmon quant.sf or kallisto
+abundance.tsv file, optionally gzip compressed. Sample quantifications are
+named by their directory and are merged, with features missing from a
+sample given a zero count. The format is determined from the file headers
+unless specified by the -format flag. The -quant flag selects whether the
+NumReads or est_counts columns (counts), or the TPM columns (tpm) are used
+for salmon and kallisto input.
+
+Versioned Ensembl identifiers (ENSG00000000000.1) may be used if the
+-strip-version flag is set, in which case version suffixes are removed
+before identifiers are matched. Transcript level counts are summed into
+gene level counts before smearing when a transcript to gene mapping is
+provided by the -tx2gene flag. The mapping may be a tab-delimited table
+with transcript identifiers in the first column and gene identifiers in
+the second, or RDF N-Triples in the form:
+
+ <transcript:ENST00000000000> <obo:SO_transcribed_from> <ensembl:ENSG00000000000> .
+
+The Gene Ontology is required to be in Owl or OBO 1.4 format. The files
+can be obtained from http://current.geneontology.org/ontology/go.owl or
+http://current.geneontology.org/ontology/go-basic.obo. The format is
+determined from the content of the file.
 
 The ENSG to GO mapping is expected to be in RDF N-Triples or N-Quads in
 the form:
 
  <obo:GO_0000000> <local:annotates> <ensembl:ENSG00000000000> .
 
-for each GO term to Ensembl gene annotation.
+for each GO term to Ensembl gene annotation. Alternatively the mapping
+may be a GAF 2.1 or 2.2 annotation file as provided by the GO Consortium.
+GAF files are detected by their !gaf-version header and the DB Object ID,
+DB Object Symbol and DB Object Synonym columns are matched against the
+gene identifiers in the counts file.
+
+Annotations may be filtered by evidence code using the -evidence and
+-exclude-evidence flags. The lists may hold evidence codes or the group
+names experimental, high-throughput, phylogenetic, computational,
+author-statement, curator-statement and electronic. Evidence codes are
+taken from the GAF Evidence Code column, or for N-Quads from a graph
+label in the form <evidence:IEA>. Annotations without evidence are
+excluded when an -evidence list is provided. GAF annotations with a NOT
+qualifier and N-Quads annotations with a graph label in the form
+<not:IEA> are never used.
+
+Counts are smeared from annotated terms to their ancestors along the
+is_a relation. Additional relations may be followed by providing them
+in the -relations flag; the part_of, regulates, negatively_regulates
+and positively_regulates relations are supported. The has_part relation
+is never followed since it does not satisfy the GO true path rule.
+Relations between terms in different GO aspects, such as a biological
+process that is part_of a cellular component, are not followed since
+each aspect is analysed separately.
+
+The optimal rank of each level matrix is calculated using the singular
+value threshold selected by the -threshold flag. The unknown-noise
+threshold is the Gavish and Donoho optimal hard threshold for an unknown
+noise level, the known-noise threshold is the Gavish and Donoho
+threshold for the noise level given by -noise, and the marchenko-pastur
+threshold is the upper edge of the Marchenko-Pastur bulk for the noise
+level given by -noise, or estimated from the median singular value if
+-noise is not set. The method, threshold and noise level are recorded in
+the summary.
+
+If -bootstrap is set to a number of replicates, genes are resampled with
+replacement to form replicates of each level matrix and the mean,
+standard deviation and 95%% percentile interval of the replicate optimal
+ranks are recorded in the summary.
+
+For each level, a term by sample matrix of the counts summed over the
+genes painted onto each term is also written to the levels
+subdirectories of the matrices and plots directories, and is summarised
+in the Levels section of the summary document. These matrices allow
+samples to be compared at a given level.
+
+If a sample design is provided with -design, the term by sample matrices
+are tested for differences between two groups of samples. The design is
+a tab-delimited file with a header row, with sample names in the first
+column, groups in the second and any categorical covariates in the
+remaining columns. The groups compared are given by -contrast, or are
+the two design groups in sorted order. For each term, the log2 fold
+change is the difference in mean log2 counts per million between the
+groups and the p-value is a two-sided permutation p-value from
+-permutations permutations of group labels within covariate strata. The
+fold changes, p-values and Benjamini-Hochberg q-values for each level
+are written to the differential directory in the output directory.
+
+For each sample and root, the level with the highest optimal rank is
+selected and reported in the Selection section of the summary document.
+If -select is normalized-rank, levels are instead compared by their
+optimal rank divided by the smaller of the level matrix dimensions. The
+-tie-break flag determines whether the shallowest or deepest of equally
+ranked levels is selected, and the depths of the other equally ranked
+levels are reported.
+
+Singular values are calculated by a full SVD for level matrices with up
+to -svd-limit elements. Larger matrices have only their -svd-rank largest
+singular values calculated by randomized truncated SVD, and this is noted
+in their summaries by SigmaPartial. Since the median singular value of
+these matrices is not known, the noise level is estimated from the part
+of the matrix not accounted for by the calculated singular values.
+
+Genes are painted onto the DAG and level matrices are analysed by at
+most -workers concurrent workers, which defaults to the number of
+available CPUs.
+
+Level matrices are written as tab-delimited tables by default. If the
+-matrix-format flag is mtx, they are instead written in Matrix Market
+coordinate format with gene and GO term names written to .rows.tsv and
+.cols.tsv sidecar files. The mtx.gz format writes the same files gzip
+compressed.
+
+If -mode is enrich, the painted DAG is instead used to test GO terms for
+over-representation of a set of foreground genes and no matrices are
+written. The foreground is either a list of gene identifiers, one per
+line, given by -foreground and tested against the universe of genes
+painted in any sample, or, for each sample, the genes with a count of at
+least -min-count tested against the genes painted in that sample. Terms
+at the level given by -depth, or all terms if -depth is negative, are
+tested using the hypergeometric upper tail (one-sided Fisher's exact
+test) and Benjamini-Hochberg q-values are calculated for each root.
+Results are written to the enrichment directory in the output directory.
 
 All input files are expected to be gzip compressed and the output is
-written uncompressed to a matrix and a plot directory. A summary
-document is written to the specified out file in JSON format corresponding
-to the following Go structs.
+written uncompressed to a matrices and a plots directory in the
+directory specified by -outdir. Existing output is only overwritten if
+-force is set. A run manifest recording the inputs, flags and completed
+output is written to manifest.jsonl in the output directory. If -resume
+is set, a previous run with the same inputs and flags is continued,
+skipping sample levels that the manifest records as complete. If the run
+is interrupted, analyses in progress are completed and the summary
+document is written for the completed work so that the run can be
+resumed. Output files are written with a .partial suffix that is
+removed when they are complete. A second interrupt exits immediately,
+removing any partially written output files. A summary document is
+written to the specified out file in JSON format corresponding to the
+following Go structs.
 
   type SummaryDoc struct {
   	// Roots is the set of roots in the Gene Ontology.
@@ -132,6 +478,17 @@ to the following Go structs.
   	// Summaries contains the summaries of a smeargol
   	// analysis.
   	Summaries [][]*Summary
+
+  	// Levels contains the summaries of the term by
+  	// sample matrices of each level, for each of the
+  	// roots. The Rows of these summaries correspond
+  	// to GO terms and the Cols to samples, and their
+  	// Name is empty.
+  	Levels [][]*Summary
+
+  	// Selection contains the selected GO level for
+  	// each sample, for each of the roots.
+  	Selection [][]*Selection
   }
 
   type Summary struct {
@@ -158,8 +515,64 @@ to the following Go structs.
   	// user-provided fraction parameters.
   	OptimalRank, FractionalRank int
 
-  	// Sigma is the complete set of singular values.
+  	// Threshold is the method used to calculate the
+  	// singular value threshold for OptimalRank, one of
+  	// unknown-noise, known-noise or marchenko-pastur.
+  	// Tau is the threshold value and Noise is the
+  	// noise level used to calculate it, or estimated
+  	// from the singular values if not provided.
+  	Threshold  string
+  	Tau, Noise float64
+
+  	// Bootstrap is the number of bootstrap replicates,
+  	// formed by resampling genes with replacement, used
+  	// to estimate the variability of OptimalRank. If it
+  	// is zero, no bootstrap was performed. RankMean and
+  	// RankStdDev are the mean and standard deviation of
+  	// the replicate optimal ranks and RankCI holds their
+  	// 2.5th and 97.5th percentiles.
+  	Bootstrap            int
+  	RankMean, RankStdDev float64
+  	RankCI               [2]float64
+
+  	// Sigma is the set of singular values. It is the
+  	// complete set unless SigmaPartial is true.
   	Sigma []float64
+
+  	// SigmaPartial indicates that Sigma holds only the
+  	// largest singular values, calculated by randomized
+  	// truncated SVD since the matrix exceeded the size
+  	// limit for a full SVD. In this case the noise level
+  	// is estimated from the residual energy of the matrix
+  	// not accounted for by Sigma, rather than from the
+  	// median singular value, and FractionalRank is
+  	// calculated using the sum of the missing singular
+  	// values implied by that noise level.
+  	SigmaPartial bool
+  }
+
+  type Selection struct {
+  	// Name is the name of the sample.
+  	Name string
+
+  	// Root is the root GO term for the selection.
+  	Root string
+
+  	// Depth is the distance from the root of the
+  	// selected level and Rank is its OptimalRank.
+  	// NormalizedRank is Rank divided by the smaller
+  	// of the level matrix's Rows and Cols.
+  	Depth, Rank    int
+  	NormalizedRank float64
+
+  	// Ties holds the depths of other levels that
+  	// are equal to the selected level under the
+  	// selection criterion.
+  	Ties []int
+
+  	// Rule describes the selection criterion and
+  	// tie-breaking rule used.
+  	Rule string
   }
 
 Copyright ©2020 Dan Kortschak. All rights reserved.
@@ -173,87 +586,223 @@ Copyright ©2020 Dan Kortschak. All rights reserved.
 		os.Exit(2)
 	}
 
+	rels, err := smear.ParseRelations(*relnames)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = validMatrixFormat(*matfmt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	thresh, err := smear.NewThreshold(*method, *noise)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sel, err := newSelector(*criteria, *tiebreak)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = validBootstrap(*boot)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *workers < 1 {
+		log.Fatalf("invalid number of workers: %d", *workers)
+	}
+
+	var dirs []string
+	switch *mode {
+	case modeSmear:
+		dirs = []string{
+			"matrices",
+			"plots",
+			filepath.Join("matrices", "levels"),
+			filepath.Join("plots", "levels"),
+		}
+		if *despath != "" {
+			dirs = append(dirs, "differential")
+		}
+	case modeEnrich:
+		if (*fgpath == "") == (*mincount <= 0) {
+			log.Fatal("enrich mode requires one of -foreground or a positive -min-count")
+		}
+		dirs = []string{"enrichment"}
+	default:
+		log.Fatalf("unknown analysis mode: %q", *mode)
+	}
+
 	log.Println(os.Args)
-	for _, d := range []string{
-		"matrices",
-		"plots",
-	} {
-		err := os.Mkdir(d, 0o755)
+
+	// Stop work cleanly on the first interrupt. A second interrupt
+	// removes any partially written output files and exits immediately.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 2)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		cancel()
+		log.Println("interrupted: finishing work in progress; interrupt again to exit immediately")
+		<-interrupt
+		for _, path := range partials.removeAll() {
+			log.Printf("removed partial output file %s", path)
+		}
+		log.Fatal("interrupted again: exiting")
+	}()
+
+	err = makeOutputDirs(*outdir, *force || *resume, dirs...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var mf *manifest
+	if *mode == modeSmear {
+		inputs := map[string]string{
+			"in":       *in,
+			"ontology": *ontopath,
+			"map":      *mappath,
+		}
+		if *txpath != "" {
+			inputs["tx2gene"] = *txpath
+		}
+		if *despath != "" {
+			inputs["design"] = *despath
+		}
+		mf, err = newManifest(*outdir, *matfmt, flag.CommandLine, inputs, *resume)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("failed to create run manifest: %v", err)
 		}
+		defer mf.Close()
 	}
 
 	log.Println("[loading count data]")
-	data, err := mappingCounts(*in)
+	data, err := mappingCounts(ctx, *in, *format, *quant)
 	if err != nil {
 		log.Fatalf("failed to load count data: %v", err)
 	}
+	if *stripver || *txpath != "" {
+		var tx2gene map[string]string
+		if *txpath != "" {
+			log.Println("[loading transcript to gene mapping]")
+			tx2gene, err = transcriptGenes(*txpath, *stripver)
+			if err != nil {
+				log.Fatalf("failed to load transcript to gene mapping: %v", err)
+			}
+		}
+		data = data.Aggregate(func(id string) string {
+			if *stripver {
+				id = smear.StripVersion(id)
+			}
+			if gene, ok := tx2gene[id]; ok {
+				return gene
+			}
+			return id
+		})
+	}
+
+	var diff *differential
+	if *despath != "" {
+		log.Println("[loading sample design]")
+		d, err := readDesign(*despath)
+		if err != nil {
+			log.Fatalf("failed to load sample design: %v", err)
+		}
+		diff, err = newDifferential(d, data, *contrast, *perms)
+		if err != nil {
+			log.Fatalf("invalid sample design: %v", err)
+		}
+	}
 
 	if *lean {
 		log.Println("[loading lean ontology]")
 	} else {
 		log.Println("[loading ontology]")
 	}
-	ontology, err := ontologyGraph(*ontopath, *lean)
+	ontology, err := loadOntology(ctx, *ontopath, *lean, rels)
 	if err != nil {
 		log.Fatalf("failed to load ontology: %v", err)
 	}
 
 	log.Println("[loading gene to ontology mappings]")
-	err = connectGeneIDsTo(ontology, *mappath, data.counts)
+	filter := smear.NewEvidenceFilter(*evidence, *exclude)
+	err = connectGeneIDsTo(ctx, ontology, *mappath, data, filter)
 	if err != nil {
 		log.Fatalf("failed to connect gene IDs to ontology: %v", err)
 	}
 
 	log.Println("[smearing counts]")
-	roots := ontology.Roots(false)
-	sort.Slice(roots, func(i, j int) bool { return roots[i].Value < roots[j].Value })
-	ontoData := distributeCounts(ontology, roots, data)
+	painting, err := smear.Distribute(ctx, ontology, data, *workers)
+	if err != nil {
+		log.Fatalf("failed to smear counts: %v", err)
+	}
+	for _, id := range painting.Unannotated() {
+		log.Printf("no GO term found for %s", id)
+	}
+	roots := painting.Roots()
+
+	if *mode == modeEnrich {
+		var foreground *smear.GeneSet
+		if *fgpath != "" {
+			var missing int
+			foreground, missing, err = readGeneList(*fgpath, data, *stripver)
+			if err != nil {
+				log.Fatalf("failed to load foreground genes: %v", err)
+			}
+			if missing != 0 {
+				log.Printf("%d foreground genes not found in count data", missing)
+			}
+		}
+		log.Println("[testing term enrichment]")
+		err = enrich(ctx, *outdir, ontology, painting, data, foreground, *mincount, *depth)
+		if err != nil {
+			log.Fatalf("failed to write enrichment: %v", err)
+		}
+		return
+	}
 
 	log.Println("[writing smeared count matrices]")
 
 	var dw *debugWriter
 	if *debug {
-		dw = newDebugWriter(ontology, ontoData, data)
+		dw = newDebugWriter(ontology, painting, data)
 	}
 
-	summaries := make([][]*Summary, len(ontoData))
+	opts := smear.RankOptions{
+		Cut:       *cut,
+		Frac:      *frac,
+		Threshold: thresh,
+		SVDLimit:  *svdlim,
+		SVDRank:   *svdrank,
+		Bootstrap: *boot,
+	}
+	summaries := make([][]*smear.Summary, len(roots))
+	levels := make([][]*smear.Summary, len(roots))
 	var wg sync.WaitGroup
-	for k := range ontoData {
+	sem := make(chan struct{}, *workers)
+	for k := range roots {
 		k := k
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			lastD := -1
-			var goTerms []string
-			walkDownSubClassesFrom(roots[k], ontology, func(r, t rdf.Term, d int) {
-				dw.record(k, d, r, t)
-
-				if lastD == -1 || d == lastD {
-					goTerms = append(goTerms, t.Value)
-					lastD = d
-					return
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			levs, err := smear.Levels(ctx, ontology, roots[k])
+			if err != nil {
+				// The walk was cancelled.
+				return
+			}
+			for _, lev := range levs {
+				for _, t := range lev.Terms {
+					dw.record(k, lev.Depth, roots[k], t)
 				}
-				lastD = d
-
-				// Write out matrices for this depth. Note that d is now
-				// referring to the next level.
-				s, err := writeCountData(r.Value, d-1, goTerms, data, ontoData[k], *cut, *frac)
-				if err != nil {
+				s, l, err := writeCountData(ctx, *outdir, *matfmt, roots[k].Value, k, lev, painting, data, opts, diff, mf)
+				if err != nil && !errors.Is(err, context.Canceled) {
 					log.Println(err)
 				}
 				summaries[k] = append(summaries[k], s...)
-				goTerms = goTerms[:0]
-				goTerms = append(goTerms, t.Value)
-			})
-
-			// Write out last depth.
-			s, err := writeCountData(roots[k].Value, lastD, goTerms, data, ontoData[k], *cut, *frac)
-			if err != nil {
-				log.Println(err)
+				if l != nil {
+					levels[k] = append(levels[k], l)
+				}
 			}
-			summaries[k] = append(summaries[k], s...)
 			sort.Slice(summaries[k], func(i, j int) bool {
 				s := summaries[k]
 				switch {
@@ -275,103 +824,160 @@ Copyright ©2020 Dan Kortschak. All rights reserved.
 		for i, r := range roots {
 			rootNames[i] = "GO:" + strip(r.Value, "<obo:GO_", ">")
 		}
-		b, err := json.MarshalIndent(SummaryDoc{rootNames, summaries}, "", "\t")
+		selections := make([][]*Selection, len(summaries))
+		for i, s := range summaries {
+			selections[i] = sel.selectLevels(s)
+		}
+		b, err := json.MarshalIndent(SummaryDoc{Roots: rootNames, Summaries: summaries, Levels: levels, Selection: selections}, "", "\t")
 		if err != nil {
 			log.Fatal(err)
 		}
-		err = ioutil.WriteFile(*out, b, 0o644)
+		err = writeFile(*out, false, func(w io.Writer) error {
+			_, err := w.Write(b)
+			return err
+		})
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
+	if ctx.Err() != nil {
+		mf.Close()
+		log.Fatal("interrupted: summaries written for completed work")
+	}
 }
 
-// writeCountData writes out a matrix of gene expression data summed according
-// to the bit vector data collected during the walk of the GO DAG. It also
-// performs an SVD of the matrix, plotting the singular values and obtaining
-// an optimal truncation for each GO level/aspect.
-func writeCountData(root string, depth int, goTerms []string, data *countData, ontoData map[string]ontoCounts, cut, frac float64) ([]*Summary, error) {
-	if len(goTerms) == 0 || len(data.geneIDs) == 0 {
-		return nil, nil
+// validBootstrap returns an error if n is not a valid number of bootstrap
+// replicates. At least two replicates are needed to estimate an interval.
+func validBootstrap(n int) error {
+	if n < 0 || n == 1 {
+		return fmt.Errorf("invalid number of bootstrap replicates: %d: must be 0 or at least 2", n)
 	}
-	root = strip(root, "<obo:", ">")
+	return nil
+}
 
-	sort.Strings(goTerms)
-	m := mat.NewDense(len(data.geneIDs), len(goTerms), nil) // Assume all samples have same genes.
-	var summaries []*Summary
-	for sample, name := range data.names {
-		for col, term := range goTerms {
-			counts, ok := ontoData[term]
-			if !ok {
-				continue
+// makeOutputDirs creates the output directories, dirs, in dir. Unless force
+// is true, it is an error for any of the directories to already hold files.
+func makeOutputDirs(dir string, force bool, dirs ...string) error {
+	for _, d := range dirs {
+		path := filepath.Join(dir, d)
+		if !force {
+			entries, err := os.ReadDir(path)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
 			}
-			for row, geneID := range data.geneIDs {
-				if counts.vector[sample].Bit(row) == 0 {
-					continue
-				}
-				m.Set(row, col, data.counts[geneID][sample])
+			if len(entries) != 0 {
+				return fmt.Errorf("output directory %s is not empty: use -force to overwrite", path)
 			}
 		}
+		err := os.MkdirAll(path, 0o755)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		path := fmt.Sprintf("%s_%s_%03d", name, root, depth)
-		s, err := optimalTruncation(path, m, cut, frac)
+// writeCountData writes out a matrix of gene expression data summed according
+// to the bit vector data collected during the painting of the GO DAG for
+// the level of the aspect with index aspect below root. It also performs an
+// SVD of the matrix, plotting the singular values and obtaining an optimal
+// truncation for each GO level/aspect using the rank options in opts.
+// Matrices and plots are written to the matrices and plots directories in
+// dir, with matrices in the given format. A term × sample matrix of the
+// counts summed over the genes painted onto each term is also analysed and
+// written to the levels subdirectories, and its summary is returned as
+// level. If diff is not nil, differential tests are performed on the term ×
+// sample matrix and the results are written to the differential directory.
+// Levels that have been completed according to the manifest are not
+// rewritten and their recorded summaries are returned. If ctx is cancelled,
+// no further matrices are analysed and the summaries for the completed work
+// are returned with the context's error.
+func writeCountData(ctx context.Context, dir, format, root string, aspect int, lev smear.Level, painting *smear.Painting, data *smear.CountData, opts smear.RankOptions, diff *differential, mf *manifest) (summaries []*smear.Summary, level *smear.Summary, err error) {
+	goTerms := lev.Terms
+	depth := lev.Depth
+	if len(goTerms) == 0 || len(data.GeneIDs) == 0 {
+		return nil, nil, nil
+	}
+	root = strip(root, "<obo:", ">")
+
+	// analyse performs the SVD of m and writes out the matrix and plot
+	// for the summary with the given name, recording the completed work
+	// in the manifest.
+	analyse := func(path, name string, rows, cols []string, m *smear.Matrix) (*smear.Summary, error) {
+		s, truncErr := smear.Rank(m, opts)
+		if s == nil {
+			return nil, fmt.Errorf("%q: %w", path, truncErr)
+		}
 		s.Name = name
 		s.Root = root
 		s.Depth = depth
-		summaries = append(summaries, s)
-		if err != nil {
-			log.Println(err)
+		if truncErr != nil {
+			truncErr = fmt.Errorf("%q: %w", path, truncErr)
+		}
+		if err := plotSummary(dir, path, s, opts.Cut); err != nil && truncErr == nil {
+			truncErr = err
 		}
-		err = writeMatrix(path, data.geneIDs, goTerms, m)
+		if truncErr != nil {
+			log.Println(truncErr)
+		}
+		err := writeMatrix(dir, path, format, rows, cols, m)
 		if err != nil {
-			return summaries, err
+			return s, err
 		}
-
-		m.Zero()
+		if truncErr == nil {
+			err = mf.complete(path, s)
+		}
+		return s, err
 	}
 
-	return summaries, nil
-}
+	sort.Strings(goTerms)
+	for sample, name := range data.Names {
+		if err := ctx.Err(); err != nil {
+			return summaries, nil, err
+		}
+		path := fmt.Sprintf("%s_%s_%03d", name, root, depth)
+		if s, ok := mf.done(path); ok {
+			summaries = append(summaries, s)
+			continue
+		}
 
-func writeMatrix(path string, rows, cols []string, data *mat.Dense) (err error) {
-	f, err := os.Create(filepath.Join("matrices", path+".tsv"))
-	if err != nil {
-		return err
+		m := painting.GeneMatrix(aspect, sample, goTerms) // Assume all samples have same genes.
+		s, err := analyse(path, name, data.GeneIDs, goTerms, m)
+		if s != nil {
+			summaries = append(summaries, s)
+		}
+		if err != nil {
+			return summaries, nil, err
+		}
 	}
-	defer func() {
-		err = f.Close()
-	}()
 
-	_, err = f.Write([]byte{'\t'})
-	if err != nil {
-		return err
+	if err := ctx.Err(); err != nil {
+		return summaries, nil, err
 	}
-	_, err = f.WriteString(strings.Join(stripSlice(cols, "<obo:", ">"), "\t"))
+
+	// Write out the term × sample matrix of counts summed over the genes
+	// painted onto each term.
+	m := painting.TermMatrix(aspect, goTerms)
+	terms := stripSlice(goTerms, "<obo:", ">")
+	name := fmt.Sprintf("%s_%03d", root, depth)
+	err = diff.write(dir, name, terms, m)
 	if err != nil {
-		return err
+		return summaries, nil, err
 	}
-	_, err = f.Write([]byte{'\n'})
-	if err != nil {
-		return err
+	path := filepath.Join("levels", name)
+	if s, ok := mf.done(path); ok {
+		return summaries, s, nil
 	}
+	level, err = analyse(path, "", terms, data.Names, m)
+	return summaries, level, err
+}
 
-	for r, id := range rows {
-		_, err = f.WriteString(id)
-		if err != nil {
-			return err
-		}
-		for c := range cols {
-			_, err = fmt.Fprintf(f, "\t%v", data.At(r, c))
-			if err != nil {
-				return err
-			}
-		}
-		_, err = f.Write([]byte{'\n'})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+// writeTSVMatrix writes the level matrix data to the matrices directory in
+// dir as a tab-delimited table with the given row and column names.
+func writeTSVMatrix(dir, path string, rows, cols []string, data *smear.Matrix) error {
+	return writeFile(filepath.Join(dir, "matrices", path+".tsv"), false, func(w io.Writer) error {
+		return data.WriteTSV(w, rows, stripSlice(cols, "<obo:", ">"))
+	})
 }
 
 func stripSlice(s []string, prefix, suffix string) []string {
@@ -0,0 +1,85 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+const (
+	testOBO = `format-version: 1.4
+
+[Term]
+id: GO:0008150
+name: biological_process
+namespace: biological_process
+
+[Term]
+id: GO:0000001
+name: process one
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+
+[Term]
+id: GO:0000002
+name: process two
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+`
+
+	testCounts = `Geneid	S1	S2
+ENSG00000000001	3	0
+ENSG00000000002	5	7
+ENSG00000000003	1	1
+`
+
+	testAnnotations = `<obo:GO_0000001> <local:annotates> <ensembl:ENSG00000000001> .
+<obo:GO_0000002> <local:annotates> <ensembl:ENSG00000000002> .
+`
+)
+
+// testPainting returns the painting of the test counts onto the test
+// ontology using the test annotations, and the counts.
+func testPainting(t *testing.T) (*smear.Painting, *smear.CountData) {
+	ctx := context.Background()
+	data, err := smear.ReadCounts(ctx, strings.NewReader(testCounts), "", smear.FormatTSV, smear.QuantCounts)
+	if err != nil {
+		t.Fatalf("unexpected error reading counts: %v", err)
+	}
+	rels, err := smear.ParseRelations("")
+	if err != nil {
+		t.Fatalf("unexpected error parsing relations: %v", err)
+	}
+	o, err := smear.LoadOntology(ctx, strings.NewReader(testOBO), true, rels)
+	if err != nil {
+		t.Fatalf("unexpected error loading ontology: %v", err)
+	}
+	err = smear.Annotate(ctx, o, strings.NewReader(testAnnotations), data, smear.EvidenceFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error annotating ontology: %v", err)
+	}
+	p, err := smear.Distribute(ctx, o, data, 1)
+	if err != nil {
+		t.Fatalf("unexpected error distributing counts: %v", err)
+	}
+	return p, data
+}
+
+func TestValidBootstrap(t *testing.T) {
+	for _, n := range []int{0, 2, 100} {
+		if err := validBootstrap(n); err != nil {
+			t.Errorf("unexpected error for %d: %v", n, err)
+		}
+	}
+	for _, n := range []int{-1, 1} {
+		if err := validBootstrap(n); err == nil {
+			t.Errorf("expected error for %d", n)
+		}
+	}
+}
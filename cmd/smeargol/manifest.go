@@ -0,0 +1,307 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+// manifestName is the name of the run manifest file in the output directory.
+const manifestName = "manifest.jsonl"
+
+// manifestHeader is the first line of a run manifest. It describes the
+// inputs to a run so that a resumed run can be checked for consistency
+// with the run that wrote the existing output.
+type manifestHeader struct {
+	// Inputs holds the SHA-256 checksums of
+	// the input files keyed by flag name.
+	Inputs map[string]string
+
+	// Flags holds the values of the flags
+	// that affect the output of the run.
+	Flags map[string]string
+}
+
+// manifestEntry is a line of a run manifest following the header. Each
+// entry records a completed sample level matrix and plot.
+type manifestEntry struct {
+	// Path is the base name of the matrix
+	// and plot files.
+	Path string
+
+	// Matrix and Plot are the SHA-256
+	// checksums of the written files.
+	// Matrix covers all the files
+	// written for the matrix.
+	Matrix, Plot string
+
+	// Summary is the summary for the level.
+	Summary *smear.Summary
+}
+
+// outputFlags is the set of flags that do not affect the content of the
+// output of a run and so are not recorded in the manifest.
+var outputFlags = map[string]bool{
+	"debug":   true,
+	"force":   true,
+	"help":    true,
+	"out":     true,
+	"outdir":  true,
+	"resume":  true,
+	"workers": true,
+
+	// Flags only used in enrich mode.
+	"depth":      true,
+	"foreground": true,
+	"min-count":  true,
+}
+
+// manifest is a run manifest. It records completed work so that
+// a failed run can be resumed without repeating completed work.
+type manifest struct {
+	dir    string
+	format string
+
+	mu        sync.Mutex
+	w         *os.File
+	completed map[string]manifestEntry
+}
+
+// newManifest returns a manifest for a run writing matrices in the given
+// format to dir with the given input files keyed by the name of the flag
+// in flags that specified them. If resume
+// is true, an existing manifest in dir is loaded and checked against the
+// current run's inputs and flags, and completed entries are retained.
+func newManifest(dir, format string, flags *flag.FlagSet, inputs map[string]string, resume bool) (*manifest, error) {
+	var hdr manifestHeader
+	hdr.Inputs = make(map[string]string)
+	for name, path := range inputs {
+		sum, err := checksum(path)
+		if err != nil {
+			return nil, err
+		}
+		hdr.Inputs[name] = sum
+	}
+	hdr.Flags = make(map[string]string)
+	flags.VisitAll(func(f *flag.Flag) {
+		// Input files are compared by content.
+		_, isInput := inputs[f.Name]
+		if !outputFlags[f.Name] && !isInput {
+			hdr.Flags[f.Name] = f.Value.String()
+		}
+	})
+
+	m := &manifest{dir: dir, format: format, completed: make(map[string]manifestEntry)}
+	path := filepath.Join(dir, manifestName)
+	if resume {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+		switch {
+		case err == nil:
+			err = m.load(f, hdr)
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			// Terminate any partially written final entry.
+			_, err = f.Write([]byte{'\n'})
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			m.w = f
+			return m, nil
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	m.w = f
+	err = m.write(hdr)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// load reads an existing manifest from r and checks that its header
+// matches want. Entries for files that are missing or do not match
+// their recorded checksums are discarded, as are partially written
+// entries.
+func (m *manifest) load(r io.Reader, want manifestHeader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<30)
+	if !sc.Scan() {
+		err := sc.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	var got manifestHeader
+	err := json.Unmarshal(sc.Bytes(), &got)
+	if err != nil {
+		return fmt.Errorf("invalid manifest header: %w", err)
+	}
+	if !reflect.DeepEqual(got.Inputs, want.Inputs) {
+		return errors.New("cannot resume: input files differ from the manifest")
+	}
+	if !reflect.DeepEqual(got.Flags, want.Flags) {
+		return errors.New("cannot resume: flags differ from the manifest")
+	}
+
+	for sc.Scan() {
+		var e manifestEntry
+		err := json.Unmarshal(sc.Bytes(), &e)
+		if err != nil {
+			// Assume this is a partially written
+			// entry from a failed run.
+			continue
+		}
+		if !m.valid(e) {
+			continue
+		}
+		m.completed[e.Path] = e
+	}
+	return sc.Err()
+}
+
+// valid returns whether the files recorded by e exist and match their
+// recorded checksums.
+func (m *manifest) valid(e manifestEntry) bool {
+	sum, err := m.matrixChecksum(e.Path)
+	if err != nil || sum != e.Matrix {
+		return false
+	}
+	sum, err = checksum(filepath.Join(m.dir, "plots", e.Path+".png"))
+	if err != nil || sum != e.Plot {
+		return false
+	}
+	return e.Summary != nil
+}
+
+// done returns the summary for the sample level matrix with the given
+// path if it has been completed.
+func (m *manifest) done(path string) (*smear.Summary, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.completed[path]
+	return e.Summary, ok
+}
+
+// complete records the sample level matrix with the given path and its
+// summary as completed.
+func (m *manifest) complete(path string, s *smear.Summary) error {
+	e := manifestEntry{Path: path, Summary: s}
+	var err error
+	e.Matrix, err = m.matrixChecksum(path)
+	if err != nil {
+		return err
+	}
+	e.Plot, err = checksum(filepath.Join(m.dir, "plots", path+".png"))
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.completed[path] = e
+	return m.write(e)
+}
+
+// matrixChecksum returns the hex encoded SHA-256 checksum of the files
+// written for the matrix with the given path.
+func (m *manifest) matrixChecksum(path string) (string, error) {
+	files := matrixFiles(path, m.format)
+	if len(files) == 1 {
+		return checksum(filepath.Join(m.dir, "matrices", files[0]))
+	}
+	h := sha256.New()
+	for _, f := range files {
+		sum, err := checksum(filepath.Join(m.dir, "matrices", f))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintln(h, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// write writes v as a single JSON line to the manifest file.
+func (m *manifest) write(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = m.w.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes the manifest file.
+func (m *manifest) Close() error {
+	return m.w.Close()
+}
+
+// checksum returns the hex encoded SHA-256 checksum of the file at path.
+// If path is a directory, the checksum covers the relative paths and
+// contents of all the regular files within it.
+func checksum(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if !fi.IsDir() {
+		err = copyFile(h, path)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%q\n", filepath.ToSlash(rel))
+		return copyFile(h, file)
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyFile copies the contents of the file at path to dst.
+func copyFile(dst io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(dst, f)
+	return err
+}
@@ -0,0 +1,309 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+var resumeTests = []struct {
+	name    string
+	args    []string
+	wantErr bool
+}{
+	{name: "same flags", args: []string{"-workers=4", "-cut=1"}},
+	{name: "different workers", args: []string{"-workers=1", "-cut=1"}},
+	{name: "default workers", args: []string{"-cut=1"}},
+	{name: "different outdir", args: []string{"-workers=4", "-cut=1", "-outdir=elsewhere"}},
+	{name: "different cut", args: []string{"-workers=4", "-cut=2"}, wantErr: true},
+}
+
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "counts.tsv")
+	err := os.WriteFile(in, []byte("Geneid\tS1\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := map[string]string{"in": in}
+
+	mf, err := newManifest(dir, "tsv", manifestFlags(t, "-workers=4", "-cut=1"), inputs, false)
+	if err != nil {
+		t.Fatalf("unexpected error creating manifest: %v", err)
+	}
+	err = mf.Close()
+	if err != nil {
+		t.Fatalf("unexpected error closing manifest: %v", err)
+	}
+
+	for _, test := range resumeTests {
+		mf, err := newManifest(dir, "tsv", manifestFlags(t, test.args...), inputs, true)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error resuming with %q: got:%v want error:%t", test.name, err, test.wantErr)
+		}
+		if err == nil {
+			mf.Close()
+		}
+	}
+}
+
+var manifestValidTests = []struct {
+	name     string
+	file     string
+	content  string
+	remove   bool
+	wantDone bool
+}{
+	{name: "unchanged", wantDone: true},
+	{name: "changed matrix", file: "matrices", content: "altered"},
+	{name: "changed plot", file: "plots", content: "altered"},
+	{name: "missing matrix", file: "matrices", remove: true},
+	{name: "missing plot", file: "plots", remove: true},
+}
+
+func TestManifestValid(t *testing.T) {
+	const path = "S1_GO_0008150_001"
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"matrices": filepath.Join(dir, "matrices", path+".tsv"),
+		"plots":    filepath.Join(dir, "plots", path+".png"),
+	}
+	writeFiles := func() {
+		for kind, name := range files {
+			err := os.WriteFile(name, []byte(kind), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	err := makeOutputDirs(dir, false, "matrices", "plots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles()
+
+	mf, err := newManifest(dir, matrixTSV, manifestFlags(t), nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error creating manifest: %v", err)
+	}
+	err = mf.complete(path, &smear.Summary{Name: "S1"})
+	if err != nil {
+		t.Fatalf("unexpected error completing entry: %v", err)
+	}
+	mf.Close()
+
+	for _, test := range manifestValidTests {
+		writeFiles()
+		if test.file != "" {
+			if test.remove {
+				err = os.Remove(files[test.file])
+			} else {
+				err = os.WriteFile(files[test.file], []byte(test.content), 0o644)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		mf, err := newManifest(dir, matrixTSV, manifestFlags(t), nil, true)
+		if err != nil {
+			t.Fatalf("unexpected error resuming manifest for %s: %v", test.name, err)
+		}
+		_, ok := mf.done(path)
+		if ok != test.wantDone {
+			t.Errorf("unexpected completion state for %s: got:%t want:%t", test.name, ok, test.wantDone)
+		}
+		mf.Close()
+	}
+}
+
+func TestManifestTruncated(t *testing.T) {
+	dir := t.TempDir()
+	err := makeOutputDirs(dir, false, "matrices", "plots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"S1_GO_0008150_001", "S2_GO_0008150_001"} {
+		for _, name := range []string{filepath.Join("matrices", path+".tsv"), filepath.Join("plots", path+".png")} {
+			err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	mf, err := newManifest(dir, matrixTSV, manifestFlags(t), nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error creating manifest: %v", err)
+	}
+	err = mf.complete("S1_GO_0008150_001", &smear.Summary{Name: "S1"})
+	if err != nil {
+		t.Fatalf("unexpected error completing entry: %v", err)
+	}
+	mf.Close()
+
+	// Simulate a run that failed while writing an entry.
+	f, err := os.OpenFile(filepath.Join(dir, manifestName), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString(`{"Path":"S2_GO_0008150_001","Matrix":"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	mf, err = newManifest(dir, matrixTSV, manifestFlags(t), nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error resuming manifest with truncated entry: %v", err)
+	}
+	if _, ok := mf.done("S1_GO_0008150_001"); !ok {
+		t.Error("expected complete entry before truncated entry to be retained")
+	}
+	if _, ok := mf.done("S2_GO_0008150_001"); ok {
+		t.Error("unexpected truncated entry retained")
+	}
+	err = mf.complete("S2_GO_0008150_001", &smear.Summary{Name: "S2"})
+	if err != nil {
+		t.Fatalf("unexpected error completing entry: %v", err)
+	}
+	mf.Close()
+
+	mf, err = newManifest(dir, matrixTSV, manifestFlags(t), nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error resuming manifest: %v", err)
+	}
+	defer mf.Close()
+	for _, path := range []string{"S1_GO_0008150_001", "S2_GO_0008150_001"} {
+		if _, ok := mf.done(path); !ok {
+			t.Errorf("expected entry for %s to be retained", path)
+		}
+	}
+}
+
+func TestWriteCountDataResume(t *testing.T) {
+	const root = "<obo:GO_0008150>"
+
+	ctx := context.Background()
+	p, data := testPainting(t)
+	lev := smear.Level{Depth: 1, Terms: []string{"<obo:GO_0000001>", "<obo:GO_0000002>"}}
+	thresh, err := smear.NewThreshold(smear.UnknownNoise, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating threshold: %v", err)
+	}
+	opts := smear.RankOptions{Frac: 0.75, Threshold: thresh, SVDLimit: math.MaxInt32}
+
+	dir := t.TempDir()
+	err = makeOutputDirs(dir, false,
+		"matrices",
+		"plots",
+		filepath.Join("matrices", "levels"),
+		filepath.Join("plots", "levels"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf, err := newManifest(dir, matrixTSV, manifestFlags(t), nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error creating manifest: %v", err)
+	}
+	want, wantLevel, err := writeCountData(ctx, dir, matrixTSV, root, 0, lev, p, data, opts, nil, mf)
+	if err != nil {
+		t.Fatalf("unexpected error writing count data: %v", err)
+	}
+	mf.Close()
+	if len(want) != len(data.Names) || wantLevel == nil {
+		t.Fatalf("unexpected summaries: got:%d level:%t want:%d level:true", len(want), wantLevel != nil, len(data.Names))
+	}
+
+	// Mark the recorded summaries so that summaries merged from
+	// the manifest can be distinguished from recalculated ones.
+	markManifest(t, filepath.Join(dir, manifestName))
+
+	mf, err = newManifest(dir, matrixTSV, manifestFlags(t), nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error resuming manifest: %v", err)
+	}
+	defer mf.Close()
+	got, gotLevel, err := writeCountData(ctx, dir, matrixTSV, root, 0, lev, p, data, opts, nil, mf)
+	if err != nil {
+		t.Fatalf("unexpected error writing resumed count data: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected number of resumed summaries: got:%d want:%d", len(got), len(want))
+	}
+	for i, s := range got {
+		if s.Name != want[i].Name || s.Tau != -1 {
+			t.Errorf("summary %d not merged from manifest: got:%s tau:%v want:%s tau:-1", i, s.Name, s.Tau, want[i].Name)
+		}
+	}
+	if gotLevel == nil || gotLevel.Tau != -1 {
+		t.Errorf("level summary not merged from manifest: %+v", gotLevel)
+	}
+}
+
+// markManifest sets the Tau of each summary recorded in the manifest at
+// path to -1.
+func markManifest(t *testing.T, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines [][]byte
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := append([]byte(nil), sc.Bytes()...)
+		if len(lines) != 0 {
+			var e manifestEntry
+			err = json.Unmarshal(line, &e)
+			if err != nil {
+				t.Fatalf("unexpected error reading manifest entry: %v", err)
+			}
+			e.Summary.Tau = -1
+			line, err = json.Marshal(e)
+			if err != nil {
+				t.Fatalf("unexpected error writing manifest entry: %v", err)
+			}
+		}
+		lines = append(lines, line)
+	}
+	f.Close()
+	if sc.Err() != nil {
+		t.Fatal(sc.Err())
+	}
+	var buf []byte
+	for _, l := range lines {
+		buf = append(append(buf, l...), '\n')
+	}
+	err = os.WriteFile(path, buf, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// manifestFlags returns a flag set holding a subset of the command's
+// flags parsed from args.
+func manifestFlags(t *testing.T, args ...string) *flag.FlagSet {
+	fs := flag.NewFlagSet("smeargol", flag.ContinueOnError)
+	fs.String("in", "", "")
+	fs.String("outdir", ".", "")
+	fs.Float64("cut", 1, "")
+	fs.Int("workers", 8, "")
+	err := fs.Parse(args)
+	if err != nil {
+		t.Fatalf("unexpected error parsing flags: %v", err)
+	}
+	return fs
+}
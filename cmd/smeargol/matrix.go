@@ -0,0 +1,81 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+// Level matrix output formats.
+const (
+	matrixTSV      = "tsv"
+	matrixMarket   = "mtx"
+	matrixMarketGz = "mtx.gz"
+)
+
+// validMatrixFormat returns an error if format is not a valid level matrix
+// output format.
+func validMatrixFormat(format string) error {
+	switch format {
+	case matrixTSV, matrixMarket, matrixMarketGz:
+		return nil
+	default:
+		return fmt.Errorf("unknown matrix format: %q", format)
+	}
+}
+
+// matrixFiles returns the names of the files written to the matrices
+// directory for the level matrix with the given path in the given format.
+// The matrix file is first.
+func matrixFiles(path, format string) []string {
+	switch format {
+	case matrixMarket:
+		return []string{path + ".mtx", path + ".rows.tsv", path + ".cols.tsv"}
+	case matrixMarketGz:
+		return []string{path + ".mtx.gz", path + ".rows.tsv.gz", path + ".cols.tsv.gz"}
+	default:
+		return []string{path + ".tsv"}
+	}
+}
+
+// writeMatrix writes the level matrix data with the given row and column
+// names to the matrices directory in dir in the given format.
+func writeMatrix(dir, path, format string, rows, cols []string, data *smear.Matrix) error {
+	if format == matrixTSV {
+		return writeTSVMatrix(dir, path, rows, cols, data)
+	}
+	files := matrixFiles(path, format)
+	compress := format == matrixMarketGz
+	err := writeFile(filepath.Join(dir, "matrices", files[0]), compress, func(w io.Writer) error {
+		return data.WriteMarket(w)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeFile(filepath.Join(dir, "matrices", files[1]), compress, func(w io.Writer) error {
+		return writeNames(w, rows)
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "matrices", files[2]), compress, func(w io.Writer) error {
+		return writeNames(w, stripSlice(cols, "<obo:", ">"))
+	})
+}
+
+// writeNames writes each of names to w on a separate line.
+func writeNames(w io.Writer, names []string) error {
+	for _, n := range names {
+		_, err := fmt.Fprintln(w, n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
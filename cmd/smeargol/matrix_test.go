@@ -0,0 +1,129 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+// testGeneMatrix returns the gene × term matrix for sample S1 of the
+// test data, and its row and column names.
+func testGeneMatrix(t *testing.T) (m *smear.Matrix, rows, cols []string) {
+	p, data := testPainting(t)
+	cols = []string{"<obo:GO_0008150>", "<obo:GO_0000001>", "<obo:GO_0000002>"}
+	return p.GeneMatrix(0, 0, cols), data.GeneIDs, cols
+}
+
+var writeMatrixTests = []struct {
+	format string
+	want   map[string]string
+}{
+	{
+		format: matrixTSV,
+		want: map[string]string{
+			"level.tsv": "\tGO_0008150\tGO_0000001\tGO_0000002\n" +
+				"ENSG00000000001\t3\t3\t0\n" +
+				"ENSG00000000002\t5\t0\t5\n" +
+				"ENSG00000000003\t0\t0\t0\n",
+		},
+	},
+	{
+		format: matrixMarket,
+		want: map[string]string{
+			"level.mtx": "%%MatrixMarket matrix coordinate real general\n" +
+				"3 3 4\n" +
+				"1 1 3\n" +
+				"2 1 5\n" +
+				"1 2 3\n" +
+				"2 3 5\n",
+			"level.rows.tsv": "ENSG00000000001\nENSG00000000002\nENSG00000000003\n",
+			"level.cols.tsv": "GO_0008150\nGO_0000001\nGO_0000002\n",
+		},
+	},
+	{
+		format: matrixMarketGz,
+		want: map[string]string{
+			"level.mtx.gz": "%%MatrixMarket matrix coordinate real general\n" +
+				"3 3 4\n" +
+				"1 1 3\n" +
+				"2 1 5\n" +
+				"1 2 3\n" +
+				"2 3 5\n",
+			"level.rows.tsv.gz": "ENSG00000000001\nENSG00000000002\nENSG00000000003\n",
+			"level.cols.tsv.gz": "GO_0008150\nGO_0000001\nGO_0000002\n",
+		},
+	},
+}
+
+func TestWriteMatrix(t *testing.T) {
+	m, rows, cols := testGeneMatrix(t)
+	for _, test := range writeMatrixTests {
+		dir := t.TempDir()
+		err := os.Mkdir(filepath.Join(dir, "matrices"), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writeMatrix(dir, "level", test.format, rows, cols, m)
+		if err != nil {
+			t.Errorf("unexpected error writing %s matrix: %v", test.format, err)
+			continue
+		}
+
+		files := matrixFiles("level", test.format)
+		if len(files) != len(test.want) {
+			t.Errorf("unexpected number of %s files: got:%d want:%d", test.format, len(files), len(test.want))
+		}
+		got := make(map[string]string)
+		for _, name := range files {
+			got[name] = readMatrixFile(t, filepath.Join(dir, "matrices", name))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected %s output:\ngot: %q\nwant:%q", test.format, got, test.want)
+		}
+	}
+}
+
+// readMatrixFile returns the contents of the file at path, decompressing
+// it if it is gzip compressed.
+func readMatrixFile(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open output: %v", err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		r, err = gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("failed to open compressed output: %v", err)
+		}
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	return string(b)
+}
+
+func TestValidMatrixFormat(t *testing.T) {
+	for _, format := range []string{matrixTSV, matrixMarket, matrixMarketGz} {
+		if err := validMatrixFormat(format); err != nil {
+			t.Errorf("unexpected error for %q: %v", format, err)
+		}
+	}
+	for _, format := range []string{"", "csv", "mtx.bz2"} {
+		if err := validMatrixFormat(format); err == nil {
+			t.Errorf("expected error for %q", format)
+		}
+	}
+}
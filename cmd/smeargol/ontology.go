@@ -0,0 +1,45 @@
+// Copyright ©2020 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+// loadOntology returns the ontology stored in the gzip compressed OBO in
+// OWL or OBO 1.4 flat file at path. See smear.LoadOntology for details.
+func loadOntology(ctx context.Context, path string, lean bool, rels smear.Relations) (*smear.Ontology, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return smear.LoadOntology(ctx, z, lean, rels)
+}
+
+// connectGeneIDsTo adds the annotations in the gzip compressed file at path
+// to the ontology. See smear.Annotate for the accepted formats.
+func connectGeneIDsTo(ctx context.Context, dst *smear.Ontology, path string, data *smear.CountData, filter smear.EvidenceFilter) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	return smear.Annotate(ctx, dst, z, data, filter)
+}
@@ -0,0 +1,132 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// partialSuffix is the suffix added to the names of output files while
+// they are being written.
+const partialSuffix = ".partial"
+
+// partials is the set of output files that are being written.
+var partials = &partialFiles{paths: make(map[string]bool)}
+
+// partialFiles tracks output files that are being written so that they
+// can be removed if the program exits before they are complete.
+type partialFiles struct {
+	mu     sync.Mutex
+	paths  map[string]bool
+	closed bool
+}
+
+// errPartialsRemoved is returned when an output file is created or completed
+// after the partial output files have been removed.
+var errPartialsRemoved = errors.New("partial output files removed")
+
+// add records that the file at path is being written.
+func (p *partialFiles) add(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errPartialsRemoved
+	}
+	p.paths[path] = true
+	return nil
+}
+
+// complete renames the partial file at path to its final name, dst, and
+// stops tracking it.
+func (p *partialFiles) complete(path, dst string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errPartialsRemoved
+	}
+	delete(p.paths, path)
+	return os.Rename(path, dst)
+}
+
+// remove removes the partial file at path and stops tracking it.
+func (p *partialFiles) remove(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	delete(p.paths, path)
+	os.Remove(path)
+}
+
+// removeAll removes all the partial files being written and returns their
+// paths in sorted order. No further output files can be written or completed
+// after removeAll has been called.
+func (p *partialFiles) removeAll() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	paths := make([]string, 0, len(p.paths))
+	for path := range p.paths {
+		os.Remove(path)
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	p.paths = nil
+	return paths
+}
+
+// writeFile creates the file at path and writes to it using fn, gzip
+// compressing the output if compress is true. The output is written to
+// a file named with the partialSuffix and is renamed to path only when
+// it has been completely written, so that an interrupted write does not
+// leave a partial file at path.
+func writeFile(path string, compress bool, fn func(io.Writer) error) (err error) {
+	tmp := path + partialSuffix
+	err = partials.add(tmp)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(tmp)
+	if err != nil {
+		partials.remove(tmp)
+		return err
+	}
+	defer func() {
+		cerr := f.Close()
+		if err == nil {
+			err = cerr
+		}
+		if err != nil {
+			partials.remove(tmp)
+			return
+		}
+		err = partials.complete(tmp, path)
+	}()
+
+	var w io.Writer = f
+	if compress {
+		z := gzip.NewWriter(f)
+		defer func() {
+			cerr := z.Close()
+			if err == nil {
+				err = cerr
+			}
+		}()
+		w = z
+	}
+	b := bufio.NewWriter(w)
+	err = fn(b)
+	if err != nil {
+		return err
+	}
+	return b.Flush()
+}
@@ -0,0 +1,135 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var errTestWrite = errors.New("write failed")
+
+var writeFileTests = []struct {
+	name        string
+	file        string
+	compress    bool
+	write       func(w io.Writer) error
+	removeAll   bool
+	wantErr     error
+	wantPartial []string
+	wantOutput  bool
+}{
+	{
+		name:       "complete",
+		file:       "out.tsv",
+		write:      func(w io.Writer) error { _, err := io.WriteString(w, "data\n"); return err },
+		wantOutput: true,
+	},
+	{
+		name:       "complete compressed",
+		file:       "out.tsv.gz",
+		compress:   true,
+		write:      func(w io.Writer) error { _, err := io.WriteString(w, "data\n"); return err },
+		wantOutput: true,
+	},
+	{
+		name: "failed",
+		file: "out.tsv",
+		write: func(w io.Writer) error {
+			_, err := io.WriteString(w, "partial")
+			if err != nil {
+				return err
+			}
+			return errTestWrite
+		},
+		wantErr: errTestWrite,
+	},
+	{
+		name:        "interrupted",
+		file:        "out.tsv",
+		write:       func(w io.Writer) error { _, err := io.WriteString(w, "partial"); return err },
+		removeAll:   true,
+		wantErr:     errPartialsRemoved,
+		wantPartial: []string{"out.tsv" + partialSuffix},
+	},
+}
+
+func TestWriteFile(t *testing.T) {
+	defer func(p *partialFiles) { partials = p }(partials)
+
+	for _, test := range writeFileTests {
+		partials = &partialFiles{paths: make(map[string]bool)}
+		dir := t.TempDir()
+		path := filepath.Join(dir, test.file)
+
+		var removed []string
+		err := writeFile(path, test.compress, func(w io.Writer) error {
+			err := test.write(w)
+			if test.removeAll {
+				removed = partials.removeAll()
+			}
+			return err
+		})
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("unexpected error for %q: got:%v want:%v", test.name, err, test.wantErr)
+		}
+		for i, p := range removed {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			removed[i] = rel
+		}
+		if !reflect.DeepEqual(removed, test.wantPartial) {
+			t.Errorf("unexpected removed partial files for %q: got:%q want:%q", test.name, removed, test.wantPartial)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var files []string
+		for _, e := range entries {
+			files = append(files, e.Name())
+		}
+		var want []string
+		if test.wantOutput {
+			want = []string{test.file}
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("unexpected files after %q: got:%q want:%q", test.name, files, want)
+		}
+
+		if test.wantOutput {
+			got := readMatrixFile(t, path)
+			if got != "data\n" {
+				t.Errorf("unexpected output for %q: got:%q want:%q", test.name, got, "data\n")
+			}
+		}
+	}
+}
+
+func TestWriteFileAfterRemoveAll(t *testing.T) {
+	defer func(p *partialFiles) { partials = p }(partials)
+	partials = &partialFiles{paths: make(map[string]bool)}
+	partials.removeAll()
+
+	path := filepath.Join(t.TempDir(), "out.tsv")
+	err := writeFile(path, false, func(w io.Writer) error { return nil })
+	if !errors.Is(err, errPartialsRemoved) {
+		t.Errorf("unexpected error: got:%v want:%v", err, errPartialsRemoved)
+	}
+	for _, p := range []string{path, path + partialSuffix} {
+		_, err = os.Stat(p)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("unexpected file %s: %v", p, err)
+		}
+	}
+}
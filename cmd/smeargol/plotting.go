@@ -7,6 +7,7 @@ package main
 import (
 	"fmt"
 	"image/color"
+	"io"
 	"math"
 	"path/filepath"
 	"strconv"
@@ -14,11 +15,19 @@ import (
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
+
+	"github.com/kortschak/smeargol/smear"
 )
 
-// plot values plots the singular values to plots/path.png along with
+// plotSummary plots the singular values of s that are not less than cut
+// to dir/plots/path.png along with its optimal and fractional thresholds.
+func plotSummary(dir, path string, s *smear.Summary, cut float64) error {
+	return plotValues(dir, path, smear.CutValues(s.Sigma, cut), s.Tau, s.FractionalValue(), s.OptimalRank, s.FractionalRank)
+}
+
+// plot values plots the singular values to dir/plots/path.png along with
 // the optimal and user specified fraction thresholds.
-func plotValues(path string, sigma []float64, tau, frac float64, rOpt, rFrac int) error {
+func plotValues(dir, path string, sigma []float64, tau, frac float64, rOpt, rFrac int) error {
 	p := plot.New()
 	p.Title.Text = fmt.Sprintf("Singular Values\n%s", path)
 	p.Y.Scale = logScale{}
@@ -44,7 +53,14 @@ func plotValues(path string, sigma []float64, tau, frac float64, rOpt, rFrac int
 
 		p.Add(values, threshOpt, threshFrac)
 	}
-	return p.Save(18*vg.Centimeter, 15*vg.Centimeter, filepath.Join("plots", path+".png"))
+	wt, err := p.WriterTo(18*vg.Centimeter, 15*vg.Centimeter, "png")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "plots", path+".png"), false, func(w io.Writer) error {
+		_, err := wt.WriteTo(w)
+		return err
+	})
 }
 
 func sliceToXYs(s []float64) plotter.XYs {
@@ -0,0 +1,179 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+// SummaryDoc is the summary document written to the -out file.
+type SummaryDoc struct {
+	// Roots is the set of roots in the Gene Ontology.
+	Roots []string
+
+	// Summaries contains the summaries of a smeargol
+	// analysis.
+	Summaries [][]*smear.Summary
+
+	// Levels contains the summaries of the term by
+	// sample matrices of each level, for each of the
+	// roots. The Rows of these summaries correspond
+	// to GO terms and the Cols to samples, and their
+	// Name is empty.
+	Levels [][]*smear.Summary
+
+	// Selection contains the selected GO level for
+	// each sample, for each of the roots.
+	Selection [][]*Selection
+}
+
+// Selection is the selected GO level for a sample and root.
+type Selection struct {
+	// Name is the name of the sample.
+	Name string
+
+	// Root is the root GO term for the selection.
+	Root string
+
+	// Depth is the distance from the root of the
+	// selected level and Rank is its OptimalRank.
+	// NormalizedRank is Rank divided by the smaller
+	// of the level matrix's Rows and Cols.
+	Depth, Rank    int
+	NormalizedRank float64
+
+	// Ties holds the depths of other levels that
+	// are equal to the selected level under the
+	// selection criterion.
+	Ties []int
+
+	// Rule describes the selection criterion and
+	// tie-breaking rule used.
+	Rule string
+}
+
+// Level selection criteria.
+const (
+	selectRank           = "rank"
+	selectNormalizedRank = "normalized-rank"
+)
+
+// Level selection tie-breaking rules.
+const (
+	tieShallowest = "shallowest"
+	tieDeepest    = "deepest"
+)
+
+// selector specifies how the best GO level is selected.
+type selector struct {
+	// criterion is the level selection criterion.
+	criterion string
+
+	// tieBreak is the rule used to choose between
+	// levels that are equal under the criterion.
+	tieBreak string
+}
+
+// newSelector returns a selector using the given criterion and tie-breaking
+// rule.
+func newSelector(criterion, tieBreak string) (selector, error) {
+	switch criterion {
+	case selectRank, selectNormalizedRank:
+	default:
+		return selector{}, fmt.Errorf("unknown selection criterion: %q", criterion)
+	}
+	switch tieBreak {
+	case tieShallowest, tieDeepest:
+	default:
+		return selector{}, fmt.Errorf("unknown tie-breaking rule: %q", tieBreak)
+	}
+	return selector{criterion: criterion, tieBreak: tieBreak}, nil
+}
+
+// rule returns a description of the selection rule.
+func (sel selector) rule() string {
+	return fmt.Sprintf("highest %s, ties broken by %s depth", sel.criterion, sel.tieBreak)
+}
+
+// score returns the value of s under the selection criterion.
+func (sel selector) score(s *smear.Summary) float64 {
+	if sel.criterion == selectNormalizedRank {
+		return normalizedRank(s)
+	}
+	return float64(s.OptimalRank)
+}
+
+// normalizedRank returns the optimal rank of s divided by the smaller of
+// its matrix dimensions.
+func normalizedRank(s *smear.Summary) float64 {
+	n := s.Rows
+	if s.Cols < n {
+		n = s.Cols
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(s.OptimalRank) / float64(n)
+}
+
+// selectLevels returns the selected level for each sample in summaries,
+// which are the summaries for a single root. The selections are sorted
+// by sample name.
+func (sel selector) selectLevels(summaries []*smear.Summary) []*Selection {
+	bySample := make(map[string][]*smear.Summary)
+	var names []string
+	for _, s := range summaries {
+		if s == nil {
+			continue
+		}
+		if _, ok := bySample[s.Name]; !ok {
+			names = append(names, s.Name)
+		}
+		bySample[s.Name] = append(bySample[s.Name], s)
+	}
+	sort.Strings(names)
+
+	selections := make([]*Selection, 0, len(names))
+	for _, name := range names {
+		levels := bySample[name]
+		sort.Slice(levels, func(i, j int) bool {
+			if sel.tieBreak == tieDeepest {
+				return levels[i].Depth > levels[j].Depth
+			}
+			return levels[i].Depth < levels[j].Depth
+		})
+
+		// The first level with the highest score is
+		// selected since levels are ordered by the
+		// tie-breaking rule.
+		best := levels[0]
+		for _, s := range levels[1:] {
+			if sel.score(s) > sel.score(best) {
+				best = s
+			}
+		}
+		var ties []int
+		for _, s := range levels {
+			if s != best && sel.score(s) == sel.score(best) {
+				ties = append(ties, s.Depth)
+			}
+		}
+		sort.Ints(ties)
+
+		selections = append(selections, &Selection{
+			Name:           name,
+			Root:           best.Root,
+			Depth:          best.Depth,
+			Rank:           best.OptimalRank,
+			NormalizedRank: normalizedRank(best),
+			Ties:           ties,
+			Rule:           sel.rule(),
+		})
+	}
+	return selections
+}
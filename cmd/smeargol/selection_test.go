@@ -0,0 +1,107 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kortschak/smeargol/smear"
+)
+
+// testSummaries is a set of level summaries for two samples with levels
+// given out of depth order. Sample S1 has a tie for the highest optimal
+// rank at depths 2 and 4, and its highest normalized rank is at depth 3.
+var testSummaries = []*smear.Summary{
+	{Name: "S2", Root: "GO_0008150", Depth: 1, Rows: 10, Cols: 4, OptimalRank: 1},
+	{Name: "S1", Root: "GO_0008150", Depth: 4, Rows: 40, Cols: 20, OptimalRank: 5},
+	{Name: "S1", Root: "GO_0008150", Depth: 1, Rows: 10, Cols: 4, OptimalRank: 2},
+	nil,
+	{Name: "S1", Root: "GO_0008150", Depth: 3, Rows: 30, Cols: 6, OptimalRank: 4},
+	{Name: "S1", Root: "GO_0008150", Depth: 2, Rows: 20, Cols: 10, OptimalRank: 5},
+	{Name: "S2", Root: "GO_0008150", Depth: 2, Rows: 20, Cols: 10, OptimalRank: 3},
+}
+
+var selectLevelsTests = []struct {
+	criterion, tieBreak string
+	want                []*Selection
+}{
+	{
+		criterion: selectRank, tieBreak: tieShallowest,
+		want: []*Selection{
+			{Name: "S1", Root: "GO_0008150", Depth: 2, Rank: 5, NormalizedRank: 0.5, Ties: []int{4}, Rule: "highest rank, ties broken by shallowest depth"},
+			{Name: "S2", Root: "GO_0008150", Depth: 2, Rank: 3, NormalizedRank: 0.3, Rule: "highest rank, ties broken by shallowest depth"},
+		},
+	},
+	{
+		criterion: selectRank, tieBreak: tieDeepest,
+		want: []*Selection{
+			{Name: "S1", Root: "GO_0008150", Depth: 4, Rank: 5, NormalizedRank: 0.25, Ties: []int{2}, Rule: "highest rank, ties broken by deepest depth"},
+			{Name: "S2", Root: "GO_0008150", Depth: 2, Rank: 3, NormalizedRank: 0.3, Rule: "highest rank, ties broken by deepest depth"},
+		},
+	},
+	{
+		criterion: selectNormalizedRank, tieBreak: tieShallowest,
+		want: []*Selection{
+			{Name: "S1", Root: "GO_0008150", Depth: 3, Rank: 4, NormalizedRank: 4.0 / 6, Rule: "highest normalized-rank, ties broken by shallowest depth"},
+			{Name: "S2", Root: "GO_0008150", Depth: 2, Rank: 3, NormalizedRank: 0.3, Rule: "highest normalized-rank, ties broken by shallowest depth"},
+		},
+	},
+}
+
+func TestSelectLevels(t *testing.T) {
+	for _, test := range selectLevelsTests {
+		sel, err := newSelector(test.criterion, test.tieBreak)
+		if err != nil {
+			t.Fatalf("unexpected error creating selector: %v", err)
+		}
+		summaries := append([]*smear.Summary(nil), testSummaries...)
+		got := sel.selectLevels(summaries)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected selection for %s with %s tie-break:", test.criterion, test.tieBreak)
+			for _, s := range got {
+				t.Logf("got: %+v", *s)
+			}
+		}
+	}
+}
+
+var newSelectorTests = []struct {
+	criterion, tieBreak string
+	wantErr             bool
+}{
+	{criterion: selectRank, tieBreak: tieShallowest},
+	{criterion: selectNormalizedRank, tieBreak: tieDeepest},
+	{criterion: "fractional-rank", tieBreak: tieShallowest, wantErr: true},
+	{criterion: selectRank, tieBreak: "random", wantErr: true},
+}
+
+func TestNewSelector(t *testing.T) {
+	for _, test := range newSelectorTests {
+		_, err := newSelector(test.criterion, test.tieBreak)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %q/%q: got:%v want error:%t", test.criterion, test.tieBreak, err, test.wantErr)
+		}
+	}
+}
+
+var normalizedRankTests = []struct {
+	s    smear.Summary
+	want float64
+}{
+	{s: smear.Summary{Rows: 10, Cols: 4, OptimalRank: 2}, want: 0.5},
+	{s: smear.Summary{Rows: 4, Cols: 10, OptimalRank: 2}, want: 0.5},
+	{s: smear.Summary{Rows: 10, Cols: 10, OptimalRank: 0}, want: 0},
+	{s: smear.Summary{Rows: 0, Cols: 10, OptimalRank: 0}, want: 0},
+}
+
+func TestNormalizedRank(t *testing.T) {
+	for _, test := range normalizedRankTests {
+		got := normalizedRank(&test.s)
+		if got != test.want {
+			t.Errorf("unexpected normalized rank for %d×%d rank %d: got:%v want:%v", test.s.Rows, test.s.Cols, test.s.OptimalRank, got, test.want)
+		}
+	}
+}
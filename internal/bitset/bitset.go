@@ -0,0 +1,366 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bitset provides a compressed set of non-negative integers.
+package bitset // import "github.com/kortschak/smeargol/internal/bitset"
+
+import (
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+const (
+	// containerBits is the number of low bits of
+	// a member held within a container.
+	containerBits = 16
+
+	// bitmapWords is the number of words in a
+	// dense container's bitmap.
+	bitmapWords = (1 << containerBits) / 64
+
+	// maxArray is the largest number of members
+	// held in a sparse container's array. Above
+	// this, a bitmap is smaller.
+	maxArray = 4096
+)
+
+// Set is a compressed set of non-negative integers. Members are
+// partitioned by their high bits into containers that hold the low bits
+// either as a sorted array when the container is sparse, or as a bitmap
+// when it is dense. The zero value is an empty set.
+type Set struct {
+	// keys holds the sorted high bits of
+	// the members of each container.
+	keys       []int
+	containers []*container
+}
+
+// container holds the low bits of the members of a Set sharing the same
+// high bits.
+type container struct {
+	// array holds the sorted low bits of the
+	// members when bitmap is nil.
+	array []uint16
+
+	// bitmap holds the members of a dense
+	// container and n is its cardinality.
+	bitmap []uint64
+	n      int
+}
+
+// Set adds i to the set.
+func (s *Set) Set(i int) {
+	if i < 0 {
+		panic("bitset: negative member")
+	}
+	key := i >> containerBits
+	k := sort.SearchInts(s.keys, key)
+	if k == len(s.keys) || s.keys[k] != key {
+		s.keys = append(s.keys, 0)
+		copy(s.keys[k+1:], s.keys[k:])
+		s.keys[k] = key
+		s.containers = append(s.containers, nil)
+		copy(s.containers[k+1:], s.containers[k:])
+		s.containers[k] = &container{}
+	}
+	s.containers[k].add(uint16(i))
+}
+
+// Test returns whether i is in the set.
+func (s *Set) Test(i int) bool {
+	if i < 0 {
+		return false
+	}
+	key := i >> containerBits
+	k := sort.SearchInts(s.keys, key)
+	if k == len(s.keys) || s.keys[k] != key {
+		return false
+	}
+	return s.containers[k].contains(uint16(i))
+}
+
+// Count returns the number of members in the set.
+func (s *Set) Count() int {
+	var n int
+	for _, c := range s.containers {
+		n += c.len()
+	}
+	return n
+}
+
+// And sets s to the intersection of x and y and returns s.
+func (s *Set) And(x, y *Set) *Set {
+	var (
+		keys       []int
+		containers []*container
+	)
+	for i, j := 0, 0; i < len(x.keys) && j < len(y.keys); {
+		switch {
+		case x.keys[i] < y.keys[j]:
+			i++
+		case x.keys[i] > y.keys[j]:
+			j++
+		default:
+			c := and(x.containers[i], y.containers[j])
+			if c.len() != 0 {
+				keys = append(keys, x.keys[i])
+				containers = append(containers, c)
+			}
+			i++
+			j++
+		}
+	}
+	s.keys = keys
+	s.containers = containers
+	return s
+}
+
+// AndCount returns the number of members in the intersection of s and x
+// without constructing the intersection.
+func (s *Set) AndCount(x *Set) int {
+	var n int
+	for i, j := 0, 0; i < len(s.keys) && j < len(x.keys); {
+		switch {
+		case s.keys[i] < x.keys[j]:
+			i++
+		case s.keys[i] > x.keys[j]:
+			j++
+		default:
+			n += andCount(s.containers[i], x.containers[j])
+			i++
+			j++
+		}
+	}
+	return n
+}
+
+// Or sets s to the union of x and y and returns s.
+func (s *Set) Or(x, y *Set) *Set {
+	keys := make([]int, 0, len(x.keys)+len(y.keys))
+	containers := make([]*container, 0, len(x.keys)+len(y.keys))
+	i, j := 0, 0
+	for i < len(x.keys) || j < len(y.keys) {
+		switch {
+		case j == len(y.keys) || (i < len(x.keys) && x.keys[i] < y.keys[j]):
+			keys = append(keys, x.keys[i])
+			containers = append(containers, x.containers[i].clone())
+			i++
+		case i == len(x.keys) || x.keys[i] > y.keys[j]:
+			keys = append(keys, y.keys[j])
+			containers = append(containers, y.containers[j].clone())
+			j++
+		default:
+			keys = append(keys, x.keys[i])
+			containers = append(containers, or(x.containers[i], y.containers[j]))
+			i++
+			j++
+		}
+	}
+	s.keys = keys
+	s.containers = containers
+	return s
+}
+
+// Do calls fn with each member of the set in increasing order.
+func (s *Set) Do(fn func(i int)) {
+	for k, c := range s.containers {
+		c.do(s.keys[k]<<containerBits, fn)
+	}
+}
+
+// Binary returns the base 2 representation of the set as a bit vector,
+// with the highest members first, padded with zeros to at least width
+// digits. The empty set with zero width is represented as "0".
+func (s *Set) Binary(width int) string {
+	top := -1
+	if n := len(s.keys); n != 0 {
+		s.containers[n-1].do(s.keys[n-1]<<containerBits, func(i int) { top = i })
+	}
+	n := top + 1
+	if n < width {
+		n = width
+	}
+	if n == 0 {
+		return "0"
+	}
+	b := []byte(strings.Repeat("0", n))
+	s.Do(func(i int) { b[n-1-i] = '1' })
+	return string(b)
+}
+
+// size returns the number of bytes used to hold the members of the set.
+func (s *Set) size() int {
+	n := cap(s.keys)*bits.UintSize/8 + cap(s.containers)*bits.UintSize/8
+	for _, c := range s.containers {
+		n += 2*cap(c.array) + 8*cap(c.bitmap) + 7*bits.UintSize/8
+	}
+	return n
+}
+
+func (c *container) len() int {
+	if c.bitmap != nil {
+		return c.n
+	}
+	return len(c.array)
+}
+
+func (c *container) add(v uint16) {
+	if c.bitmap != nil {
+		w := &c.bitmap[v/64]
+		if *w&(1<<(v%64)) == 0 {
+			*w |= 1 << (v % 64)
+			c.n++
+		}
+		return
+	}
+	k := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= v })
+	if k < len(c.array) && c.array[k] == v {
+		return
+	}
+	if len(c.array) == maxArray {
+		c.toBitmap()
+		c.add(v)
+		return
+	}
+	c.array = append(c.array, 0)
+	copy(c.array[k+1:], c.array[k:])
+	c.array[k] = v
+}
+
+func (c *container) contains(v uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap[v/64]&(1<<(v%64)) != 0
+	}
+	k := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= v })
+	return k < len(c.array) && c.array[k] == v
+}
+
+func (c *container) do(base int, fn func(i int)) {
+	if c.bitmap == nil {
+		for _, v := range c.array {
+			fn(base + int(v))
+		}
+		return
+	}
+	for k, w := range c.bitmap {
+		for w != 0 {
+			fn(base + k*64 + bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+}
+
+func (c *container) clone() *container {
+	if c.bitmap != nil {
+		return &container{bitmap: append([]uint64(nil), c.bitmap...), n: c.n}
+	}
+	return &container{array: append([]uint16(nil), c.array...)}
+}
+
+// toBitmap converts a sparse container to a dense container.
+func (c *container) toBitmap() {
+	c.bitmap = make([]uint64, bitmapWords)
+	for _, v := range c.array {
+		c.bitmap[v/64] |= 1 << (v % 64)
+	}
+	c.n = len(c.array)
+	c.array = nil
+}
+
+// toArray converts a dense container to a sparse container.
+func (c *container) toArray() {
+	c.array = make([]uint16, 0, c.n)
+	c.do(0, func(i int) { c.array = append(c.array, uint16(i)) })
+	c.bitmap = nil
+	c.n = 0
+}
+
+// and returns the intersection of x and y.
+func and(x, y *container) *container {
+	if x.bitmap != nil && y.bitmap != nil {
+		c := &container{bitmap: make([]uint64, bitmapWords)}
+		for k := range c.bitmap {
+			c.bitmap[k] = x.bitmap[k] & y.bitmap[k]
+			c.n += bits.OnesCount64(c.bitmap[k])
+		}
+		if c.n <= maxArray {
+			c.toArray()
+		}
+		return c
+	}
+	if x.bitmap != nil {
+		x, y = y, x
+	}
+	c := &container{}
+	for _, v := range x.array {
+		if y.contains(v) {
+			c.array = append(c.array, v)
+		}
+	}
+	return c
+}
+
+// andCount returns the cardinality of the intersection of x and y.
+func andCount(x, y *container) int {
+	if x.bitmap != nil && y.bitmap != nil {
+		var n int
+		for k := range x.bitmap {
+			n += bits.OnesCount64(x.bitmap[k] & y.bitmap[k])
+		}
+		return n
+	}
+	if x.bitmap != nil {
+		x, y = y, x
+	}
+	var n int
+	for _, v := range x.array {
+		if y.contains(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// or returns the union of x and y.
+func or(x, y *container) *container {
+	if x.bitmap == nil && y.bitmap == nil {
+		c := &container{array: make([]uint16, 0, len(x.array)+len(y.array))}
+		i, j := 0, 0
+		for i < len(x.array) || j < len(y.array) {
+			switch {
+			case j == len(y.array) || (i < len(x.array) && x.array[i] < y.array[j]):
+				c.array = append(c.array, x.array[i])
+				i++
+			case i == len(x.array) || x.array[i] > y.array[j]:
+				c.array = append(c.array, y.array[j])
+				j++
+			default:
+				c.array = append(c.array, x.array[i])
+				i++
+				j++
+			}
+		}
+		if len(c.array) > maxArray {
+			c.toBitmap()
+		}
+		return c
+	}
+	if x.bitmap == nil {
+		x, y = y, x
+	}
+	c := x.clone()
+	if y.bitmap != nil {
+		c.n = 0
+		for k := range c.bitmap {
+			c.bitmap[k] |= y.bitmap[k]
+			c.n += bits.OnesCount64(c.bitmap[k])
+		}
+		return c
+	}
+	for _, v := range y.array {
+		c.add(v)
+	}
+	return c
+}
@@ -0,0 +1,199 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bitset
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+var setTests = []struct {
+	name    string
+	max     int
+	members int
+}{
+	{name: "empty", max: 1000, members: 0},
+	{name: "sparse", max: 1 << 20, members: 100},
+	{name: "dense", max: 1 << 17, members: 1 << 16},
+	{name: "array limit", max: 1 << 16, members: maxArray + 1},
+	{name: "mixed", max: 1 << 18, members: 20000},
+}
+
+func randomSet(rnd *rand.Rand, max, members int) (*Set, *big.Int) {
+	var s Set
+	var b big.Int
+	for i := 0; i < members; i++ {
+		v := rnd.Intn(max)
+		s.Set(v)
+		b.SetBit(&b, v, 1)
+	}
+	return &s, &b
+}
+
+func members(s *Set) []int {
+	var m []int
+	s.Do(func(i int) { m = append(m, i) })
+	return m
+}
+
+func bigMembers(b *big.Int) []int {
+	var m []int
+	for i := 0; i < b.BitLen(); i++ {
+		if b.Bit(i) != 0 {
+			m = append(m, i)
+		}
+	}
+	return m
+}
+
+func checkSet(t *testing.T, name string, got *Set, want *big.Int) {
+	t.Helper()
+	gotMembers := members(got)
+	wantMembers := bigMembers(want)
+	if fmt.Sprint(gotMembers) != fmt.Sprint(wantMembers) {
+		t.Errorf("unexpected members for %s: got:%v want:%v", name, gotMembers, wantMembers)
+	}
+	if !sort.IntsAreSorted(gotMembers) {
+		t.Errorf("members not sorted for %s", name)
+	}
+	if got.Count() != len(wantMembers) {
+		t.Errorf("unexpected count for %s: got:%d want:%d", name, got.Count(), len(wantMembers))
+	}
+	for _, i := range wantMembers {
+		if !got.Test(i) {
+			t.Errorf("missing member %d for %s", i, name)
+			break
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, test := range setTests {
+		x, bx := randomSet(rnd, test.max, test.members)
+		y, by := randomSet(rnd, test.max, test.members)
+		checkSet(t, test.name, x, bx)
+
+		checkSet(t, test.name+" and", new(Set).And(x, y), new(big.Int).And(bx, by))
+		checkSet(t, test.name+" or", new(Set).Or(x, y), new(big.Int).Or(bx, by))
+		if got, want := x.AndCount(y), len(bigMembers(new(big.Int).And(bx, by))); got != want {
+			t.Errorf("unexpected intersection count for %s: got:%d want:%d", test.name, got, want)
+		}
+
+		// Check that aliased receivers are handled.
+		z, bz := randomSet(rnd, test.max, test.members)
+		z.Or(z, x)
+		bz.Or(bz, bx)
+		checkSet(t, test.name+" aliased or", z, bz)
+		z.And(z, y)
+		bz.And(bz, by)
+		checkSet(t, test.name+" aliased and", z, bz)
+
+		if test.members != 0 && x.Test(test.max) {
+			t.Errorf("unexpected member %d for %s", test.max, test.name)
+		}
+	}
+}
+
+func TestBinary(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, width := range []int{0, 10, 100, 1000} {
+		for _, n := range []int{0, 1, 10, 50} {
+			s, b := randomSet(rnd, 500, n)
+			got := s.Binary(width)
+			want := fmt.Sprintf("%0*b", width, b)
+			if got != want {
+				t.Errorf("unexpected binary representation for width %d with %d members:\ngot: %s\nwant:%s", width, n, got, want)
+			}
+		}
+	}
+}
+
+// The benchmarks below model the gene sets of GO terms for a human-scale
+// dataset of 60000 genes. Most terms hold few genes and terms near the
+// roots hold most genes.
+const humanGenes = 60000
+
+var termSizes = []int{10, 100, 1000, 10000, 50000}
+
+func BenchmarkSetMemory(b *testing.B) {
+	for _, n := range termSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			rnd := rand.New(rand.NewSource(1))
+			var bytes int
+			for i := 0; i < b.N; i++ {
+				s, _ := randomSet(rnd, humanGenes, n)
+				bytes += s.size()
+			}
+			b.ReportMetric(float64(bytes)/float64(b.N), "bytes/set")
+		})
+	}
+}
+
+func BenchmarkBigIntMemory(b *testing.B) {
+	for _, n := range termSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			rnd := rand.New(rand.NewSource(1))
+			var bytes int
+			for i := 0; i < b.N; i++ {
+				_, v := randomSet(rnd, humanGenes, n)
+				bytes += 8 * cap(v.Bits())
+			}
+			b.ReportMetric(float64(bytes)/float64(b.N), "bytes/set")
+		})
+	}
+}
+
+func BenchmarkSetAndCount(b *testing.B) {
+	for _, n := range termSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			rnd := rand.New(rand.NewSource(1))
+			x, _ := randomSet(rnd, humanGenes, n)
+			y, _ := randomSet(rnd, humanGenes, humanGenes/2)
+			var z Set
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				z.And(x, y).Count()
+			}
+		})
+	}
+}
+
+func BenchmarkSetAndCountNoAlloc(b *testing.B) {
+	for _, n := range termSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			rnd := rand.New(rand.NewSource(1))
+			x, _ := randomSet(rnd, humanGenes, n)
+			y, _ := randomSet(rnd, humanGenes, humanGenes/2)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				x.AndCount(y)
+			}
+		})
+	}
+}
+
+func BenchmarkBigIntAndCount(b *testing.B) {
+	for _, n := range termSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			rnd := rand.New(rand.NewSource(1))
+			_, x := randomSet(rnd, humanGenes, n)
+			_, y := randomSet(rnd, humanGenes, humanGenes/2)
+			var z big.Int
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				z.And(x, y)
+				var c int
+				for _, w := range z.Bits() {
+					c += bits.OnesCount(uint(w))
+				}
+			}
+		})
+	}
+}
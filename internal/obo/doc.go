@@ -0,0 +1,9 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package obo implements decoding the OBO 1.4 flat file encoding of a Gene
+// Ontology dataset into the RDF statements that would be obtained from the
+// equivalent OBO in OWL encoding. It is not a complete OBO parser
+// implementation.
+package obo // import "github.com/kortschak/smeargol/internal/obo"
@@ -0,0 +1,664 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package obo
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+)
+
+const (
+	oboNS      = "http://purl.obolibrary.org/obo/"
+	oboInOwlNS = "http://www.geneontology.org/formats/oboInOwl#"
+	owlNS      = "http://www.w3.org/2002/07/owl#"
+	rdfNS      = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rdfsNS     = "http://www.w3.org/2000/01/rdf-schema#"
+	xsdNS      = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// namespaces is the set of namespaces used for the IRIs emitted by the
+// decoder, ordered longest to shortest to ensure prefixes are not eagerly
+// chosen during compaction.
+var namespaces = func() []xml.Attr {
+	ns := []xml.Attr{
+		{Name: xml.Name{Space: "xmlns", Local: "obo"}, Value: oboNS},
+		{Name: xml.Name{Space: "xmlns", Local: "oboInOwl"}, Value: oboInOwlNS},
+		{Name: xml.Name{Space: "xmlns", Local: "owl"}, Value: owlNS},
+		{Name: xml.Name{Space: "xmlns", Local: "rdf"}, Value: rdfNS},
+		{Name: xml.Name{Space: "xmlns", Local: "rdfs"}, Value: rdfsNS},
+		{Name: xml.Name{Space: "xmlns", Local: "xsd"}, Value: xsdNS},
+	}
+	sort.Sort(byLength(ns))
+	return ns
+}()
+
+// relations maps the relationship type names used in GO OBO files to
+// their OBO Foundry identifiers. The Typedef stanzas in OBO files are
+// written after the Term stanzas, so the mapping cannot be learned from
+// the stream before it is needed.
+var relations = map[string]string{
+	"part_of":              "BFO:0000050",
+	"has_part":             "BFO:0000051",
+	"occurs_in":            "BFO:0000066",
+	"happens_during":       "RO:0002092",
+	"ends_during":          "RO:0002093",
+	"regulates":            "RO:0002211",
+	"negatively_regulates": "RO:0002212",
+	"positively_regulates": "RO:0002213",
+}
+
+// synonymPredicates maps OBO synonym scopes to OBO in OWL predicates.
+var synonymPredicates = map[string]string{
+	"EXACT":   oboInOwlNS + "hasExactSynonym",
+	"BROAD":   oboInOwlNS + "hasBroadSynonym",
+	"NARROW":  oboInOwlNS + "hasNarrowSynonym",
+	"RELATED": oboInOwlNS + "hasRelatedSynonym",
+}
+
+// Decoder is a Gene Ontology OBO 1.4 decoder. rdf.Statements returned
+// by calls to the Unmarshal and UnmarshalLocal methods are those that
+// would be obtained from the OBO in OWL encoding of the tags that are
+// handled by the decoder, and have their Terms' UID fields set so that
+// unique terms will have unique IDs and so can be used directly in a
+// graph.Multi, or in a graph.Graph if all predicate terms are identical.
+// IDs created by the decoder all exist within a single namespace and so
+// Terms can be uniquely identified by their UID. Term UIDs are based
+// from 1 to allow RDF-aware client graphs to assign ID if no ID has been
+// assigned.
+//
+// The Term stanza tags handled are id, name, namespace, def, comment,
+// alt_id, subset, synonym, xref, is_a, relationship and is_obsolete.
+// The Typedef stanza tags handled are id, name, namespace and xref.
+// Instance stanzas and other tags are ignored.
+type Decoder struct {
+	r    *bufio.Reader
+	line int
+
+	// next is the header of the next
+	// stanza in the stream.
+	next string
+
+	ontology         string
+	defaultNamespace string
+
+	strings store
+	ids     map[string]int64
+
+	curr int
+	buf  []*rdf.Statement
+	seen map[[3]int64]bool
+}
+
+// NewDecoder returns a new Decoder that takes input from r.
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	dec := &Decoder{
+		strings: make(store),
+		ids:     make(map[string]int64),
+		seen:    make(map[[3]int64]bool),
+	}
+	err := dec.readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return dec, nil
+}
+
+// Reset resets the decoder to use the provided io.Reader, retaining
+// the existing Term ID mapping. The ontology header is obtained from the
+// OBO stream in r.
+func (dec *Decoder) Reset(r io.Reader) error {
+	for i := range dec.buf[dec.curr:] {
+		dec.buf[dec.curr+i] = nil
+	}
+	dec.curr = 0
+	dec.buf = dec.buf[:0]
+	dec.strings = make(store)
+	return dec.readHeader(r)
+}
+
+// Namespaces returns the namespaces used to compact IRIs by the
+// UnmarshalLocal method.
+func (dec *Decoder) Namespaces() []xml.Attr {
+	return namespaces
+}
+
+// Unmarshal returns the next unique statement from the input stream.
+func (dec *Decoder) Unmarshal() (*rdf.Statement, error) {
+	for {
+		for len(dec.buf[dec.curr:]) == 0 {
+			err := dec.fillBuffer()
+			if err != nil {
+				return nil, err
+			}
+		}
+		s := dec.buf[dec.curr]
+		dec.buf[dec.curr] = nil
+		dec.curr++
+		if len(dec.buf[dec.curr:]) == 0 {
+			dec.curr = 0
+			dec.buf = dec.buf[:0]
+		}
+		s.Subject.Value = dec.strings.intern(s.Subject.Value)
+		s.Predicate.Value = dec.strings.intern(s.Predicate.Value)
+		s.Object.Value = dec.strings.intern(s.Object.Value)
+		s.Subject.UID = dec.idFor(s.Subject.Value)
+		s.Object.UID = dec.idFor(s.Object.Value)
+		s.Predicate.UID = dec.idFor(s.Predicate.Value)
+		triple := [3]int64{s.Subject.UID, s.Predicate.UID, s.Object.UID}
+		if !dec.seen[triple] {
+			dec.seen[triple] = true
+			return s, nil
+		}
+	}
+}
+
+// UnmarshalLocal returns the next unique statement from the input stream, but
+// replaces full IRI namespace text with the qualified name prefix obtained
+// from the decoder's namespaces. The namespaces can be obtained by using the
+// Namespaces method.
+func (dec *Decoder) UnmarshalLocal() (*rdf.Statement, error) {
+	s, err := dec.Unmarshal()
+	if err != nil {
+		return nil, err
+	}
+	subj, err := compactTerm(s.Subject)
+	if err != nil {
+		return s, err
+	}
+	s.Subject = subj
+	pred, err := compactTerm(s.Predicate)
+	if err != nil {
+		return s, err
+	}
+	s.Predicate = pred
+	obj, err := compactTerm(s.Object)
+	if err != nil {
+		return s, err
+	}
+	s.Object = obj
+	return s, nil
+}
+
+func compactTerm(term rdf.Term) (rdf.Term, error) {
+	text, qual, kind, err := term.Parts()
+	if err != nil {
+		return term, err
+	}
+	uid := term.UID
+	switch kind {
+	case rdf.IRI:
+		new, changed := compactIRI(text)
+		if changed {
+			term, err := rdf.NewIRITerm(new)
+			if err != nil {
+				return term, err
+			}
+			term.UID = uid
+			return term, nil
+		}
+	case rdf.Literal:
+		if qual == "" {
+			return term, nil
+		}
+		new, changed := compactIRI(qual)
+		if changed {
+			term, err := rdf.NewLiteralTerm(text, new)
+			if err != nil {
+				return term, err
+			}
+			term.UID = uid
+			return term, nil
+		}
+	}
+	return term, nil
+}
+
+func compactIRI(iri string) (new string, changed bool) {
+	for _, ns := range namespaces {
+		if strings.HasPrefix(iri, ns.Value) {
+			suffix := strings.TrimPrefix(iri, ns.Value)
+			if len(suffix) == 0 {
+				return iri, false
+			}
+			return ns.Name.Local + ":" + suffix, true
+		}
+	}
+	return iri, false
+}
+
+func (dec *Decoder) idFor(s string) int64 {
+	id, ok := dec.ids[s]
+	if ok {
+		return id
+	}
+	id = int64(len(dec.ids)) + 1
+	dec.ids[s] = id
+	return id
+}
+
+// tagValue is a single tag-value pair from an OBO stanza.
+type tagValue struct {
+	line  int
+	tag   string
+	value string
+}
+
+// readHeader reads the OBO header frame from r up to the first stanza.
+func (dec *Decoder) readHeader(r io.Reader) error {
+	dec.r = bufio.NewReader(r)
+	dec.line = 0
+	dec.next = ""
+	dec.ontology = ""
+	dec.defaultNamespace = ""
+
+	header, err := dec.readFrame()
+	if err != nil {
+		return err
+	}
+	if len(header) == 0 && dec.next == "" {
+		return io.ErrUnexpectedEOF
+	}
+	for _, tv := range header {
+		switch tv.tag {
+		case "format-version":
+		case "ontology":
+			dec.ontology = unescape(stripTrailing(tv.value))
+		case "default-namespace":
+			dec.defaultNamespace = unescape(stripTrailing(tv.value))
+		}
+	}
+	if dec.ontology != "" {
+		subj, err := rdf.NewIRITerm(oboNS + dec.ontology + ".owl")
+		if err != nil {
+			return dec.errorf(header[0].line, "invalid ontology: %v", err)
+		}
+		dec.buf = append(dec.buf, &rdf.Statement{Subject: subj, Predicate: iri(rdfNS + "type"), Object: iri(owlNS + "Ontology")})
+	}
+	return nil
+}
+
+// readFrame reads tag-value lines up to the next stanza header or the end
+// of the stream. The stanza header is stored in dec.next.
+func (dec *Decoder) readFrame() ([]tagValue, error) {
+	var frame []tagValue
+	for {
+		line, err := dec.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				dec.next = ""
+				return frame, nil
+			}
+			return nil, err
+		}
+		dec.line++
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "", line[0] == '!':
+			continue
+		case line[0] == '[':
+			if line[len(line)-1] != ']' {
+				return nil, dec.errorf(dec.line, "invalid stanza header: %q", line)
+			}
+			dec.next = line[1 : len(line)-1]
+			return frame, nil
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, dec.errorf(dec.line, "missing tag separator: %q", line)
+		}
+		frame = append(frame, tagValue{
+			line:  dec.line,
+			tag:   strings.TrimSpace(line[:i]),
+			value: strings.TrimSpace(line[i+1:]),
+		})
+	}
+}
+
+func (dec *Decoder) fillBuffer() error {
+	if dec.next == "" {
+		dec.strings = nil
+		return io.EOF
+	}
+	kind := dec.next
+	line := dec.line
+	frame, err := dec.readFrame()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case "Term":
+		dec.buf, err = dec.term(dec.buf, frame, line)
+	case "Typedef":
+		dec.buf, err = dec.typedef(dec.buf, frame, line)
+	}
+	return err
+}
+
+// term collects the statements for a Term stanza.
+func (dec *Decoder) term(dst []*rdf.Statement, frame []tagValue, line int) ([]*rdf.Statement, error) {
+	id, ok := idOf(frame)
+	if !ok {
+		return dst, dec.errorf(line, "missing id in Term stanza")
+	}
+	subj, err := rdf.NewIRITerm(dec.iriFor(id))
+	if err != nil {
+		return dst, dec.errorf(line, "invalid id %q: %v", id, err)
+	}
+
+	dst = append(dst,
+		&rdf.Statement{Subject: subj, Predicate: iri(rdfNS + "type"), Object: iri(owlNS + "Class")},
+		&rdf.Statement{Subject: subj, Predicate: iri(oboInOwlNS + "id"), Object: str(id)},
+	)
+	hasNamespace := false
+	for _, tv := range frame {
+		var (
+			pred rdf.Term
+			obj  rdf.Term
+		)
+		switch tv.tag {
+		case "name":
+			pred = iri(rdfsNS + "label")
+			obj = str(unescape(stripTrailing(tv.value)))
+		case "namespace":
+			hasNamespace = true
+			pred = iri(oboInOwlNS + "hasOBONamespace")
+			obj = str(unescape(stripTrailing(tv.value)))
+		case "def":
+			text, _, err := quoted(tv.value)
+			if err != nil {
+				return dst, dec.errorf(tv.line, "invalid def: %v", err)
+			}
+			pred = iri(oboNS + "IAO_0000115")
+			obj = str(text)
+		case "comment":
+			pred = iri(rdfsNS + "comment")
+			obj = str(unescape(stripTrailing(tv.value)))
+		case "alt_id":
+			pred = iri(oboInOwlNS + "hasAlternativeId")
+			obj = str(unescape(stripTrailing(tv.value)))
+		case "subset":
+			pred = iri(oboInOwlNS + "inSubset")
+			obj, err = rdf.NewIRITerm(dec.iriFor(unescape(stripTrailing(tv.value))))
+			if err != nil {
+				return dst, dec.errorf(tv.line, "invalid subset: %v", err)
+			}
+		case "synonym":
+			text, rest, err := quoted(tv.value)
+			if err != nil {
+				return dst, dec.errorf(tv.line, "invalid synonym: %v", err)
+			}
+			scope := "RELATED"
+			if f := strings.Fields(rest); len(f) != 0 && synonymPredicates[f[0]] != "" {
+				scope = f[0]
+			}
+			pred = iri(synonymPredicates[scope])
+			obj = str(text)
+		case "xref":
+			f := strings.Fields(stripTrailing(tv.value))
+			if len(f) == 0 {
+				continue
+			}
+			pred = iri(oboInOwlNS + "hasDbXref")
+			obj = str(unescape(f[0]))
+		case "is_a":
+			pred = iri(rdfsNS + "subClassOf")
+			obj, err = rdf.NewIRITerm(dec.iriFor(unescape(stripTrailing(tv.value))))
+			if err != nil {
+				return dst, dec.errorf(tv.line, "invalid is_a: %v", err)
+			}
+		case "relationship":
+			f := strings.Fields(stripTrailing(tv.value))
+			if len(f) != 2 {
+				return dst, dec.errorf(tv.line, "invalid relationship: %q", tv.value)
+			}
+			dst, err = dec.restriction(dst, subj, f[0], f[1])
+			if err != nil {
+				return dst, dec.errorf(tv.line, "invalid relationship: %v", err)
+			}
+			continue
+		case "is_obsolete":
+			if stripTrailing(tv.value) != "true" {
+				continue
+			}
+			pred = iri(owlNS + "deprecated")
+			obj = literal("true", xsdNS+"boolean")
+		default:
+			continue
+		}
+		dst = append(dst, &rdf.Statement{Subject: subj, Predicate: pred, Object: obj})
+	}
+	if !hasNamespace && dec.defaultNamespace != "" {
+		dst = append(dst, &rdf.Statement{Subject: subj, Predicate: iri(oboInOwlNS + "hasOBONamespace"), Object: str(dec.defaultNamespace)})
+	}
+	return dst, nil
+}
+
+// restriction collects the statements for an existential restriction
+// expressed by an OBO relationship tag.
+//
+//  <subj> <rdfs:subClassOf> _:blank .
+//  _:blank <rdf:type> <owl:Restriction> .
+//  _:blank <owl:onProperty> <rel> .
+//  _:blank <owl:someValuesFrom> <filler> .
+func (dec *Decoder) restriction(dst []*rdf.Statement, subj rdf.Term, rel, filler string) ([]*rdf.Statement, error) {
+	if id, ok := relations[rel]; ok {
+		rel = id
+	}
+	prop, err := rdf.NewIRITerm(dec.iriFor(rel))
+	if err != nil {
+		return dst, err
+	}
+	obj, err := rdf.NewIRITerm(dec.iriFor(filler))
+	if err != nil {
+		return dst, err
+	}
+	blank, err := rdf.NewBlankTerm(blankLabel(subj.Value, prop.Value, obj.Value))
+	if err != nil {
+		return dst, err
+	}
+	return append(dst,
+		&rdf.Statement{Subject: subj, Predicate: iri(rdfsNS + "subClassOf"), Object: blank},
+		&rdf.Statement{Subject: blank, Predicate: iri(rdfNS + "type"), Object: iri(owlNS + "Restriction")},
+		&rdf.Statement{Subject: blank, Predicate: iri(owlNS + "onProperty"), Object: prop},
+		&rdf.Statement{Subject: blank, Predicate: iri(owlNS + "someValuesFrom"), Object: obj},
+	), nil
+}
+
+// typedef collects the statements for a Typedef stanza.
+func (dec *Decoder) typedef(dst []*rdf.Statement, frame []tagValue, line int) ([]*rdf.Statement, error) {
+	id, ok := idOf(frame)
+	if !ok {
+		return dst, dec.errorf(line, "missing id in Typedef stanza")
+	}
+	rel := id
+	if xref, ok := relations[id]; ok {
+		rel = xref
+	}
+	for _, tv := range frame {
+		if tv.tag == "xref" {
+			if f := strings.Fields(stripTrailing(tv.value)); len(f) != 0 {
+				rel = unescape(f[0])
+				break
+			}
+		}
+	}
+	subj, err := rdf.NewIRITerm(dec.iriFor(rel))
+	if err != nil {
+		return dst, dec.errorf(line, "invalid id %q: %v", id, err)
+	}
+
+	dst = append(dst,
+		&rdf.Statement{Subject: subj, Predicate: iri(rdfNS + "type"), Object: iri(owlNS + "ObjectProperty")},
+		&rdf.Statement{Subject: subj, Predicate: iri(oboInOwlNS + "id"), Object: str(rel)},
+		&rdf.Statement{Subject: subj, Predicate: iri(oboInOwlNS + "shorthand"), Object: str(id)},
+	)
+	for _, tv := range frame {
+		var pred rdf.Term
+		switch tv.tag {
+		case "name":
+			pred = iri(rdfsNS + "label")
+		case "namespace":
+			pred = iri(oboInOwlNS + "hasOBONamespace")
+		default:
+			continue
+		}
+		dst = append(dst, &rdf.Statement{Subject: subj, Predicate: pred, Object: str(unescape(stripTrailing(tv.value)))})
+	}
+	return dst, nil
+}
+
+// iriFor returns the IRI for an OBO identifier. Prefixed identifiers are
+// expanded into the OBO Foundry namespace. Unprefixed identifiers are
+// placed in the ontology's local namespace.
+func (dec *Decoder) iriFor(id string) string {
+	switch {
+	case strings.HasPrefix(id, "http://"), strings.HasPrefix(id, "https://"):
+		return id
+	case strings.Contains(id, ":"):
+		return oboNS + strings.Replace(id, ":", "_", 1)
+	default:
+		return oboNS + dec.ontology + "#" + id
+	}
+}
+
+func (dec *Decoder) errorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("obo: line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// idOf returns the value of the id tag in the frame.
+func idOf(frame []tagValue) (id string, ok bool) {
+	for _, tv := range frame {
+		if tv.tag == "id" {
+			id = unescape(stripTrailing(tv.value))
+			return id, id != ""
+		}
+	}
+	return "", false
+}
+
+// stripTrailing removes trailing comments and trailing modifiers from an
+// OBO tag value.
+func stripTrailing(v string) string {
+	var inQuote bool
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuote = !inQuote
+		case '!':
+			if !inQuote {
+				v = v[:i]
+			}
+		}
+	}
+	v = strings.TrimSpace(v)
+	if strings.HasSuffix(v, "}") {
+		if i := strings.LastIndex(v, "{"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+	}
+	return v
+}
+
+// quoted returns the unescaped text of a quoted string at the start of v
+// and the remainder of v following the closing quote.
+func quoted(v string) (text, rest string, err error) {
+	if !strings.HasPrefix(v, `"`) {
+		return "", v, fmt.Errorf("missing opening quote: %q", v)
+	}
+	for i := 1; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case '"':
+			return unescape(v[1:i]), strings.TrimSpace(v[i+1:]), nil
+		}
+	}
+	return "", v, fmt.Errorf("missing closing quote: %q", v)
+}
+
+// unescape returns v with OBO escape sequences replaced.
+func unescape(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+	var buf strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c == '\\' && i+1 < len(v) {
+			i++
+			switch v[i] {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'W':
+				c = ' '
+			default:
+				c = v[i]
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
+
+func iri(s string) rdf.Term {
+	t, err := rdf.NewIRITerm(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func str(s string) rdf.Term {
+	return literal(s, xsdNS+"string")
+}
+
+func literal(text, qual string) rdf.Term {
+	t, err := rdf.NewLiteralTerm(text, qual)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func blankLabel(parts ...string) string {
+	h := md5.New()
+	for _, p := range parts {
+		h.Write([]byte(p)) //nolint:errcheck
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// store is a string internment implementation.
+type store map[string]string
+
+// intern returns an interned version of the parameter.
+func (is store) intern(s string) string {
+	if s == "" {
+		return ""
+	}
+	t, ok := is[s]
+	if ok {
+		return t
+	}
+	is[s] = s
+	return s
+}
+
+type byLength []xml.Attr
+
+func (a byLength) Len() int           { return len(a) }
+func (a byLength) Less(i, j int) bool { return len(a[i].Value) > len(a[j].Value) }
+func (a byLength) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,149 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package obo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pkg/diff"
+	"github.com/pkg/diff/write"
+)
+
+var oboTests = []struct {
+	name string
+	obo  string
+	want string
+}{
+	{
+		name: "go",
+		obo: `format-version: 1.2
+data-version: releases/2021-05-01
+subsetdef: goslim_generic "Generic GO slim"
+default-namespace: gene_ontology
+ontology: go
+
+[Term]
+id: GO:0000001
+name: mitochondrion inheritance
+namespace: biological_process
+def: "The distribution of \"mitochondria\" into daughter cells." [GOC:mcc, PMID:10873824]
+synonym: "mitochondrial inheritance" EXACT []
+is_a: GO:0048308 ! organelle inheritance
+is_a: GO:0048311 ! mitochondrion distribution
+
+[Term]
+id: GO:0000015
+name: phosphopyruvate hydratase complex
+namespace: cellular_component
+subset: goslim_generic
+xref: Wikipedia:Enolase {source="x"}
+is_a: GO:1902494 ! catalytic complex
+relationship: part_of GO:0005829 ! cytosol
+
+[Term]
+id: GO:0000005
+name: obsolete ribosomal chaperone activity
+is_obsolete: true
+
+[Typedef]
+id: part_of
+name: part of
+xref: BFO:0000050
+is_transitive: true
+`,
+		want: `<obo:go.owl> <rdf:type> <owl:Ontology> .
+<obo:GO_0000001> <rdf:type> <owl:Class> .
+<obo:GO_0000001> <oboInOwl:id> "GO:0000001"^^<xsd:string> .
+<obo:GO_0000001> <rdfs:label> "mitochondrion inheritance"^^<xsd:string> .
+<obo:GO_0000001> <oboInOwl:hasOBONamespace> "biological_process"^^<xsd:string> .
+<obo:GO_0000001> <obo:IAO_0000115> "The distribution of \"mitochondria\" into daughter cells."^^<xsd:string> .
+<obo:GO_0000001> <oboInOwl:hasExactSynonym> "mitochondrial inheritance"^^<xsd:string> .
+<obo:GO_0000001> <rdfs:subClassOf> <obo:GO_0048308> .
+<obo:GO_0000001> <rdfs:subClassOf> <obo:GO_0048311> .
+<obo:GO_0000015> <rdf:type> <owl:Class> .
+<obo:GO_0000015> <oboInOwl:id> "GO:0000015"^^<xsd:string> .
+<obo:GO_0000015> <rdfs:label> "phosphopyruvate hydratase complex"^^<xsd:string> .
+<obo:GO_0000015> <oboInOwl:hasOBONamespace> "cellular_component"^^<xsd:string> .
+<obo:GO_0000015> <oboInOwl:inSubset> <obo:go#goslim_generic> .
+<obo:GO_0000015> <oboInOwl:hasDbXref> "Wikipedia:Enolase"^^<xsd:string> .
+<obo:GO_0000015> <rdfs:subClassOf> <obo:GO_1902494> .
+<obo:GO_0000015> <rdfs:subClassOf> _:4975f72232a6de5b7f88a2a8d4d064cf .
+_:4975f72232a6de5b7f88a2a8d4d064cf <rdf:type> <owl:Restriction> .
+_:4975f72232a6de5b7f88a2a8d4d064cf <owl:onProperty> <obo:BFO_0000050> .
+_:4975f72232a6de5b7f88a2a8d4d064cf <owl:someValuesFrom> <obo:GO_0005829> .
+<obo:GO_0000005> <rdf:type> <owl:Class> .
+<obo:GO_0000005> <oboInOwl:id> "GO:0000005"^^<xsd:string> .
+<obo:GO_0000005> <rdfs:label> "obsolete ribosomal chaperone activity"^^<xsd:string> .
+<obo:GO_0000005> <owl:deprecated> "true"^^<xsd:boolean> .
+<obo:GO_0000005> <oboInOwl:hasOBONamespace> "gene_ontology"^^<xsd:string> .
+<obo:BFO_0000050> <rdf:type> <owl:ObjectProperty> .
+<obo:BFO_0000050> <oboInOwl:id> "BFO:0000050"^^<xsd:string> .
+<obo:BFO_0000050> <oboInOwl:shorthand> "part_of"^^<xsd:string> .
+<obo:BFO_0000050> <rdfs:label> "part of"^^<xsd:string> .
+`,
+	},
+}
+
+func TestOBO(t *testing.T) {
+	for _, test := range oboTests {
+		dec, err := NewDecoder(strings.NewReader(test.obo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for {
+			s, err := dec.UnmarshalLocal()
+			if err != nil {
+				if err != io.EOF {
+					t.Errorf("error during decoding: %v", err)
+				}
+				break
+			}
+			got = append(got, s.String())
+		}
+		want := strings.Split(strings.TrimSpace(test.want), "\n")
+		sort.Strings(got)
+		sort.Strings(want)
+
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			var buf bytes.Buffer
+			err := diff.Text("got", "want", strings.Join(got, "\n")+"\n", strings.Join(want, "\n")+"\n", &buf, write.TerminalColor())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			t.Errorf("unexpected statements for %q:\n%s", test.name, &buf)
+		}
+	}
+}
+
+func TestOBOErrors(t *testing.T) {
+	for _, test := range []struct {
+		obo  string
+		want string
+	}{
+		{obo: "", want: io.ErrUnexpectedEOF.Error()},
+		{obo: "format-version: 1.2\n[Term\n", want: `obo: line 2: invalid stanza header: "[Term"`},
+		{obo: "format-version: 1.2\n\n[Term]\nname: x\n", want: "obo: line 3: missing id in Term stanza"},
+		{obo: "format-version: 1.2\n\n[Term]\nid: GO:0000001\ndef: no quotes\n", want: `obo: line 5: invalid def: missing opening quote: "no quotes"`},
+	} {
+		dec, err := NewDecoder(strings.NewReader(test.obo))
+		if err == nil {
+			for {
+				_, err = dec.Unmarshal()
+				if err != nil {
+					break
+				}
+			}
+		}
+		if fmt.Sprint(err) != test.want {
+			t.Errorf("unexpected error for %q: got:%v want:%s", test.obo, err, test.want)
+		}
+	}
+}
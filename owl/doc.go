@@ -0,0 +1,22 @@
+// Copyright ©2020 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package owl implements decoding the RDF/XML encoding of OBO in OWL
+// ontologies, such as the Gene Ontology, Uberon, CL and ChEBI, into RDF
+// statements. It is not a complete RDF/XML parser implementation.
+//
+// The Decoder handles the top-level owl:AnnotationProperty, owl:Axiom,
+// owl:Class, owl:ObjectProperty and owl:Ontology elements of an rdf:RDF
+// document according to the OBO in OWL mapping. Any other top-level
+// element, such as owl:NamedIndividual, owl:DatatypeProperty or
+// rdf:Description, and property elements within a handled element that
+// are not part of the mapping, are converted to statements by a generic
+// RDF/XML conversion. The set of element kinds that statements are emitted
+// for can be restricted using Options, and Options.Strict can be used to
+// reject top-level elements outside the mapping with an error holding the
+// position of the element.
+//
+// Errors in the input are returned as a *SyntaxError holding the position
+// and name of the element being decoded and the underlying cause.
+package owl // import "github.com/kortschak/smeargol/owl"
@@ -46,26 +46,29 @@ type annotationProperty struct {
 	Label           []rdfDataType `xml:"label"`
 	Shorthand       []rdfDataType `xml:"shorthand"`
 	SubPropertyOf   []rdfDataType `xml:"subPropertyOf"`
+
+	Other []element `xml:",any"`
 }
 
-func (a annotationProperty) collect(dst []*rdf.Statement) []*rdf.Statement {
+func (a annotationProperty) collect(dst []*rdf.Statement, base string) ([]*rdf.Statement, error) {
+	subj, err := rdf.NewIRITerm(a.About)
+	if err != nil {
+		return dst, err
+	}
 	claim, text, qual, kind := a.ID.claim()
-	if kind != rdf.Invalid {
-		subj := mustTerm(rdf.NewIRITerm(a.About))
-		pred := mustTerm(rdf.NewIRITerm(claim))
-		var obj rdf.Term
-		switch kind {
-		case rdf.IRI:
-			obj = mustTerm(rdf.NewIRITerm(text))
-		case rdf.Literal:
-			obj = mustTerm(rdf.NewLiteralTerm(text, qual))
-		}
-		s := &rdf.Statement{Subject: subj, Predicate: pred, Object: obj}
-		dst = append(dst, s)
+	dst, err = appendClaim(dst, subj, claim, text, qual, kind)
+	if err != nil {
+		return dst, err
+	}
+	dst, err = labelType(dst, subj, a.XMLName)
+	if err != nil {
+		return dst, err
 	}
-	dst = labelType(dst, a.About, a.XMLName)
-	dst = collect(dst, a.About, a)
-	return dst
+	dst, err = collect(dst, subj, a)
+	if err != nil {
+		return dst, err
+	}
+	return collectOther(dst, base, subj, a.Other)
 }
 
 type axiom struct {
@@ -78,34 +81,52 @@ type axiom struct {
 	Comment   []rdfDataType `xml:"comment"`
 	HasDbXref []rdfDataType `xml:"hasDbXref"`
 	Label     []rdfDataType `xml:"label"`
+
+	Other []element `xml:",any"`
 }
 
-func (a axiom) collect(dst []*rdf.Statement) []*rdf.Statement {
+func (a axiom) collect(dst []*rdf.Statement, base string) ([]*rdf.Statement, error) {
 	label := blankLabel(md5.New(),
 		a.Source.Resource,
 		a.Property.Resource,
 		a.Target.Resource, a.Target.Text, a.Target.Datatype)
-	blank := mustTerm(rdf.NewBlankTerm(label))
+	blank, err := rdf.NewBlankTerm(label)
+	if err != nil {
+		return dst, err
+	}
 
-	typ := mustTerm(rdf.NewIRITerm(a.XMLName.Space + a.XMLName.Local))
+	typ, err := iriTerm(a.XMLName)
+	if err != nil {
+		return dst, err
+	}
 
-	source := mustTerm(rdf.NewIRITerm(a.Source.Resource))
-	sourcePred := mustTerm(rdf.NewIRITerm(a.Source.XMLName.Space + a.Source.XMLName.Local))
+	source, err := rdf.NewIRITerm(a.Source.Resource)
+	if err != nil {
+		return dst, err
+	}
+	sourcePred, err := iriTerm(a.Source.XMLName)
+	if err != nil {
+		return dst, err
+	}
 
-	property := mustTerm(rdf.NewIRITerm(a.Property.Resource))
-	propertyPred := mustTerm(rdf.NewIRITerm(a.Property.XMLName.Space + a.Property.XMLName.Local))
+	property, err := rdf.NewIRITerm(a.Property.Resource)
+	if err != nil {
+		return dst, err
+	}
+	propertyPred, err := iriTerm(a.Property.XMLName)
+	if err != nil {
+		return dst, err
+	}
 
 	_, text, qual, kind := a.Target.claim()
-	var target rdf.Term
-	switch kind {
-	case rdf.Literal:
-		target = mustTerm(rdf.NewLiteralTerm(text, qual))
-	case rdf.IRI:
-		target = mustTerm(rdf.NewIRITerm(text))
-	default:
-		panic(fmt.Sprintf("unexpected term kind: %s", kind))
+	target, err := newTerm(text, qual, kind)
+	if err != nil {
+		return dst, err
+	}
+	targetPred, err := iriTerm(a.Target.XMLName)
+	if err != nil {
+		return dst, err
 	}
-	targetPred := mustTerm(rdf.NewIRITerm(a.Target.XMLName.Space + a.Target.XMLName.Local))
 
 	dst = append(dst,
 		&rdf.Statement{Subject: blank, Predicate: rdfType, Object: typ},
@@ -120,22 +141,20 @@ func (a axiom) collect(dst []*rdf.Statement) []*rdf.Statement {
 		a.Comment,
 	} {
 		for _, p := range links {
-			pred := mustTerm(rdf.NewIRITerm(p.XMLName.Space + p.XMLName.Local))
+			pred, err := iriTerm(p.XMLName)
+			if err != nil {
+				return dst, err
+			}
 			_, text, qual, kind := p.claim()
-			var obj rdf.Term
-			switch kind {
-			case rdf.Literal:
-				obj = mustTerm(rdf.NewLiteralTerm(text, qual))
-			case rdf.IRI:
-				obj = mustTerm(rdf.NewIRITerm(text))
-			default:
-				panic(fmt.Sprintf("unexpected term kind: %s", kind))
+			obj, err := newTerm(text, qual, kind)
+			if err != nil {
+				return dst, err
 			}
 			dst = append(dst, &rdf.Statement{Subject: blank, Predicate: pred, Object: obj})
 		}
 	}
 
-	return dst
+	return collectOther(dst, base, blank, a.Other)
 }
 
 type class struct {
@@ -167,29 +186,38 @@ type class struct {
 
 	EquivalentClass equivalentClasses `xml:"equivalentClass"`
 	SubClassOf      subClassOfs       `xml:"subClassOf"`
+
+	Other []element `xml:",any"`
 }
 
-func (c class) collect(dst []*rdf.Statement) []*rdf.Statement {
+func (c class) collect(dst []*rdf.Statement, base string) ([]*rdf.Statement, error) {
+	subj, err := rdf.NewIRITerm(c.About)
+	if err != nil {
+		return dst, err
+	}
 	claim, text, qual, kind := c.ID.claim()
-	if kind != rdf.Invalid {
-		subj := mustTerm(rdf.NewIRITerm(c.About))
-		pred := mustTerm(rdf.NewIRITerm(claim))
-		var obj rdf.Term
-		switch kind {
-		case rdf.IRI:
-			obj = mustTerm(rdf.NewIRITerm(text))
-		case rdf.Literal:
-			obj = mustTerm(rdf.NewLiteralTerm(text, qual))
-		}
-		s := &rdf.Statement{Subject: subj, Predicate: pred, Object: obj}
-		dst = append(dst, s)
+	dst, err = appendClaim(dst, subj, claim, text, qual, kind)
+	if err != nil {
+		return dst, err
 	}
 
-	dst = labelType(dst, c.About, c.XMLName)
-	dst = collect(dst, c.About, c)
-	dst = c.SubClassOf.collect(dst, c.About)
-	dst = c.EquivalentClass.collect(dst, c.About, c.XMLName)
-	return dst
+	dst, err = labelType(dst, subj, c.XMLName)
+	if err != nil {
+		return dst, err
+	}
+	dst, err = collect(dst, subj, c)
+	if err != nil {
+		return dst, err
+	}
+	dst, err = c.SubClassOf.collect(dst, c.About)
+	if err != nil {
+		return dst, err
+	}
+	dst, err = c.EquivalentClass.collect(dst, c.About, c.XMLName)
+	if err != nil {
+		return dst, err
+	}
+	return collectOther(dst, base, subj, c.Other)
 }
 
 type subClassOf struct {
@@ -208,28 +236,26 @@ func (s subClassOf) claim() (pred, obj, qual string, kind rdf.Kind) {
 
 type subClassOfs []subClassOf
 
-func (l subClassOfs) collect(dst []*rdf.Statement, about string) []*rdf.Statement {
+func (l subClassOfs) collect(dst []*rdf.Statement, about string) ([]*rdf.Statement, error) {
 	if l == nil {
-		return dst
+		return dst, nil
+	}
+	subj, err := rdf.NewIRITerm(about)
+	if err != nil {
+		return dst, err
 	}
 	for _, c := range l {
 		claim, text, qual, kind := c.claim()
-		if kind != rdf.Invalid {
-			subj := mustTerm(rdf.NewIRITerm(about))
-			pred := mustTerm(rdf.NewIRITerm(claim))
-			var obj rdf.Term
-			switch kind {
-			case rdf.IRI:
-				obj = mustTerm(rdf.NewIRITerm(text))
-			case rdf.Literal:
-				obj = mustTerm(rdf.NewLiteralTerm(text, qual))
-			}
-			s := &rdf.Statement{Subject: subj, Predicate: pred, Object: obj}
-			dst = append(dst, s)
+		dst, err = appendClaim(dst, subj, claim, text, qual, kind)
+		if err != nil {
+			return dst, err
+		}
+		dst, err = c.Restriction.collect(dst, about, c.XMLName)
+		if err != nil {
+			return dst, err
 		}
-		dst = c.Restriction.collect(dst, about, c.XMLName)
 	}
-	return dst
+	return dst, nil
 }
 
 type equivalentClass struct {
@@ -267,15 +293,21 @@ func (e description) claim() (pred, obj, qual string, kind rdf.Kind) {
 
 type equivalentClasses []equivalentClass
 
-func (l equivalentClasses) collect(dst []*rdf.Statement, about string, in xml.Name) []*rdf.Statement {
+func (l equivalentClasses) collect(dst []*rdf.Statement, about string, in xml.Name) ([]*rdf.Statement, error) {
 	if l == nil {
-		return dst
+		return dst, nil
 	}
 
 	h := md5.New()
 
-	subj := mustTerm(rdf.NewIRITerm(about))
-	typ := mustTerm(rdf.NewIRITerm(in.Space + in.Local))
+	subj, err := rdf.NewIRITerm(about)
+	if err != nil {
+		return dst, err
+	}
+	typ, err := iriTerm(in)
+	if err != nil {
+		return dst, err
+	}
 	s := &rdf.Statement{Subject: subj, Predicate: rdfType, Object: typ}
 	dst = append(dst, s)
 
@@ -286,13 +318,18 @@ func (l equivalentClasses) collect(dst []*rdf.Statement, about string, in xml.Na
 			about, in.Space, in.Local, ecLabel,
 		)
 
-		pred := mustTerm(rdf.NewIRITerm(equivalentClass.XMLName.Space + equivalentClass.XMLName.Local))
-		ecBlank := mustTerm(rdf.NewBlankTerm(ecLabel))
+		pred, err := iriTerm(equivalentClass.XMLName)
+		if err != nil {
+			return dst, err
+		}
+		ecBlank, err := rdf.NewBlankTerm(ecLabel)
+		if err != nil {
+			return dst, err
+		}
 		s := &rdf.Statement{Subject: subj, Predicate: pred, Object: ecBlank}
 		dst = append(dst, s)
 
 		for _, class := range equivalentClass.Class {
-			typ := mustTerm(rdf.NewIRITerm(in.Space + in.Local))
 			s := &rdf.Statement{Subject: ecBlank, Predicate: rdfType, Object: typ}
 			dst = append(dst, s)
 
@@ -307,12 +344,18 @@ func (l equivalentClasses) collect(dst []*rdf.Statement, about string, in xml.Na
 					cLabel, intersectionOf.ParseType,
 				)
 
-				iBlank := mustTerm(rdf.NewBlankTerm(iLabel))
+				iBlank, err := rdf.NewBlankTerm(iLabel)
+				if err != nil {
+					return dst, err
+				}
 
 				if i == 0 {
 					// _:ecBlank <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#Class> .
 					// _:ecBlank <http://www.w3.org/2002/07/owl#intersectionOf> _:iBlank .
-					pred := mustTerm(rdf.NewIRITerm(intersectionOf.XMLName.Space + intersectionOf.XMLName.Local))
+					pred, err := iriTerm(intersectionOf.XMLName)
+					if err != nil {
+						return dst, err
+					}
 					dst = append(dst,
 						&rdf.Statement{Subject: ecBlank, Predicate: rdfType, Object: typ},
 						&rdf.Statement{Subject: ecBlank, Predicate: pred, Object: iBlank},
@@ -329,33 +372,38 @@ func (l equivalentClasses) collect(dst []*rdf.Statement, about string, in xml.Na
 
 					_, text, qual, kind := description.claim()
 					if kind != rdf.Invalid {
-						var obj rdf.Term
-						switch kind {
-						case rdf.IRI:
-							obj = mustTerm(rdf.NewIRITerm(text))
-						case rdf.Literal:
-							obj = mustTerm(rdf.NewLiteralTerm(text, qual))
+						obj, err := newTerm(text, qual, kind)
+						if err != nil {
+							return dst, err
 						}
-
 						s = &rdf.Statement{Subject: iBlank, Predicate: rdfFirst, Object: obj}
 						dst = append(dst, s)
 					}
 
 					// _:iBlank <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:nextIBlank .
 					iLabel = blankLabel(h, iLabel)
-					nextIBlank := mustTerm(rdf.NewBlankTerm(iLabel))
+					nextIBlank, err := rdf.NewBlankTerm(iLabel)
+					if err != nil {
+						return dst, err
+					}
 					s = &rdf.Statement{Subject: iBlank, Predicate: rdfRest, Object: nextIBlank}
 					dst = append(dst, s)
 					iBlank = nextIBlank
 
 					if i < len(intersectionOf.Restriction) {
-						dst = intersectionOf.Restriction[i].collect(dst, iLabel, firstName)
+						dst, err = intersectionOf.Restriction[i].collect(dst, iLabel, firstName)
+						if err != nil {
+							return dst, err
+						}
 					}
 
 					if i < len(intersectionOf.Description)-1 {
 						// _:iBlank <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:nextIBlank .
 						iLabel = blankLabel(h, iLabel)
-						nextIBlank := mustTerm(rdf.NewBlankTerm(iLabel))
+						nextIBlank, err := rdf.NewBlankTerm(iLabel)
+						if err != nil {
+							return dst, err
+						}
 						s = &rdf.Statement{Subject: iBlank, Predicate: rdfRest, Object: nextIBlank}
 						iBlank = nextIBlank
 					} else {
@@ -367,7 +415,7 @@ func (l equivalentClasses) collect(dst []*rdf.Statement, about string, in xml.Na
 			}
 		}
 	}
-	return dst
+	return dst, nil
 }
 
 type restriction struct {
@@ -381,17 +429,26 @@ func (r restriction) claim() (pred, obj, qual string, kind rdf.Kind) {
 	return "", r.XMLName.Space + r.XMLName.Local, "", rdf.IRI
 }
 
-func (r restriction) collect(dst []*rdf.Statement, about string, in xml.Name) []*rdf.Statement {
+func (r restriction) collect(dst []*rdf.Statement, about string, in xml.Name) ([]*rdf.Statement, error) {
 	h := md5.New()
 
 	// Rule swarmlet: try IRI and then blank node.
 	subj, err := rdf.NewIRITerm(about)
 	if err != nil {
-		subj = mustTerm(rdf.NewBlankTerm(about))
+		subj, err = rdf.NewBlankTerm(about)
+		if err != nil {
+			return dst, err
+		}
 	}
 
-	parent := mustTerm(rdf.NewIRITerm(in.Space + in.Local))
-	typ := mustTerm(rdf.NewIRITerm(r.XMLName.Space + r.XMLName.Local))
+	parent, err := iriTerm(in)
+	if err != nil {
+		return dst, err
+	}
+	typ, err := iriTerm(r.XMLName)
+	if err != nil {
+		return dst, err
+	}
 
 	label := blankLabel(h,
 		r.XMLName.Space, r.XMLName.Local,
@@ -399,7 +456,10 @@ func (r restriction) collect(dst []*rdf.Statement, about string, in xml.Name) []
 		r.OnProperty.Resource, r.OnProperty.Text,
 		r.SomeValuesFrom.Resource, r.SomeValuesFrom.Text)
 
-	blank := mustTerm(rdf.NewBlankTerm(label))
+	blank, err := rdf.NewBlankTerm(label)
+	if err != nil {
+		return dst, err
+	}
 
 	dst = append(dst,
 		&rdf.Statement{Subject: subj, Predicate: parent, Object: blank},
@@ -409,36 +469,28 @@ func (r restriction) collect(dst []*rdf.Statement, about string, in xml.Name) []
 		r.OnProperty,
 		r.SomeValuesFrom,
 	} {
-		if claim, text, qual, kind := d.claim(); kind != rdf.Invalid {
-			pred := mustTerm(rdf.NewIRITerm(claim))
-			var obj rdf.Term
-			switch kind {
-			case rdf.IRI:
-				obj = mustTerm(rdf.NewIRITerm(text))
-			case rdf.Literal:
-				obj = mustTerm(rdf.NewLiteralTerm(text, qual))
-			}
-			s := &rdf.Statement{Subject: blank, Predicate: pred, Object: obj}
-			dst = append(dst, s)
+		claim, text, qual, kind := d.claim()
+		dst, err = appendClaim(dst, blank, claim, text, qual, kind)
+		if err != nil {
+			return dst, err
 		}
 	}
 
-	return dst
+	return dst, nil
 }
 
 // https://www.w3.org/TR/owl-ref/#Restriction
 type restrictions []restriction
 
-func (l restrictions) collect(dst []*rdf.Statement, about string, in xml.Name) []*rdf.Statement {
-	if len(l) == 0 {
-		return dst
-	}
-
+func (l restrictions) collect(dst []*rdf.Statement, about string, in xml.Name) ([]*rdf.Statement, error) {
 	for _, r := range l {
-		dst = r.collect(dst, about, in)
+		var err error
+		dst, err = r.collect(dst, about, in)
+		if err != nil {
+			return dst, err
+		}
 	}
-
-	return dst
+	return dst, nil
 }
 
 type objectProperty struct {
@@ -456,6 +508,8 @@ type objectProperty struct {
 	Type            []rdfDataType `xml:"type"`
 
 	PropertyChainAxiom *propertyChainAxiom `xml:"propertyChainAxiom"`
+
+	Other []element `xml:",any"`
 }
 
 type propertyChainAxiom struct {
@@ -465,49 +519,68 @@ type propertyChainAxiom struct {
 	Description []description `xml:"Description"`
 }
 
-func (o objectProperty) collect(dst []*rdf.Statement) []*rdf.Statement {
+func (o objectProperty) collect(dst []*rdf.Statement, base string) ([]*rdf.Statement, error) {
+	subj, err := rdf.NewIRITerm(o.About)
+	if err != nil {
+		return dst, err
+	}
 	claim, text, qual, kind := o.ID.claim()
-	if kind != rdf.Invalid {
-		subj := mustTerm(rdf.NewIRITerm(o.About))
-		pred := mustTerm(rdf.NewIRITerm(claim))
-		var obj rdf.Term
-		switch kind {
-		case rdf.IRI:
-			obj = mustTerm(rdf.NewIRITerm(text))
-		case rdf.Literal:
-			obj = mustTerm(rdf.NewLiteralTerm(text, qual))
-		}
-		s := &rdf.Statement{Subject: subj, Predicate: pred, Object: obj}
-		dst = append(dst, s)
+	dst, err = appendClaim(dst, subj, claim, text, qual, kind)
+	if err != nil {
+		return dst, err
+	}
+	dst, err = labelType(dst, subj, o.XMLName)
+	if err != nil {
+		return dst, err
+	}
+	dst, err = collect(dst, subj, o)
+	if err != nil {
+		return dst, err
+	}
+	dst, err = o.PropertyChainAxiom.collect(dst, o.About)
+	if err != nil {
+		return dst, err
 	}
-	dst = labelType(dst, o.About, o.XMLName)
-	dst = collect(dst, o.About, o)
-	dst = o.PropertyChainAxiom.collect(dst, o.About)
-	return dst
+	return collectOther(dst, base, subj, o.Other)
 }
 
-func (c *propertyChainAxiom) collect(dst []*rdf.Statement, about string) []*rdf.Statement {
+func (c *propertyChainAxiom) collect(dst []*rdf.Statement, about string) ([]*rdf.Statement, error) {
 	if c == nil {
-		return dst
+		return dst, nil
 	}
 
 	h := md5.New()
 	label := blankLabel(h, about, c.XMLName.Space+c.XMLName.Local)
 
-	subj := mustTerm(rdf.NewIRITerm(about))
-	pred := mustTerm(rdf.NewIRITerm(c.XMLName.Space + c.XMLName.Local))
-	blank := mustTerm(rdf.NewBlankTerm(label))
+	subj, err := rdf.NewIRITerm(about)
+	if err != nil {
+		return dst, err
+	}
+	pred, err := iriTerm(c.XMLName)
+	if err != nil {
+		return dst, err
+	}
+	blank, err := rdf.NewBlankTerm(label)
+	if err != nil {
+		return dst, err
+	}
 	s := &rdf.Statement{Subject: subj, Predicate: pred, Object: blank}
 	dst = append(dst, s)
 
 	for i, d := range c.Description {
-		obj := mustTerm(rdf.NewIRITerm(d.About))
+		obj, err := rdf.NewIRITerm(d.About)
+		if err != nil {
+			return dst, err
+		}
 		s := &rdf.Statement{Subject: blank, Predicate: rdfFirst, Object: obj}
 		dst = append(dst, s)
 		if i < len(c.Description)-1 {
 			// _:blank <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:nextBlank .
 			label = blankLabel(h, label)
-			nextBlank := mustTerm(rdf.NewBlankTerm(label))
+			nextBlank, err := rdf.NewBlankTerm(label)
+			if err != nil {
+				return dst, err
+			}
 			s = &rdf.Statement{Subject: blank, Predicate: rdfRest, Object: nextBlank}
 			blank = nextBlank
 		} else {
@@ -516,7 +589,7 @@ func (c *propertyChainAxiom) collect(dst []*rdf.Statement, about string) []*rdf.
 		}
 		dst = append(dst, s)
 	}
-	return dst
+	return dst, nil
 }
 
 type ontology struct {
@@ -530,20 +603,34 @@ type ontology struct {
 	License             []rdfDataType `xml:"license"`
 	Title               []rdfDataType `xml:"title"`
 	VersionIRI          []rdfDataType `xml:"versionIRI"`
+
+	Other []element `xml:",any"`
 }
 
-func (o ontology) collect(dst []*rdf.Statement) []*rdf.Statement {
-	dst = labelType(dst, o.About, o.XMLName)
-	dst = collect(dst, o.About, o)
-	return dst
+func (o ontology) collect(dst []*rdf.Statement, base string) ([]*rdf.Statement, error) {
+	subj, err := rdf.NewIRITerm(o.About)
+	if err != nil {
+		return dst, err
+	}
+	dst, err = labelType(dst, subj, o.XMLName)
+	if err != nil {
+		return dst, err
+	}
+	dst, err = collect(dst, subj, o)
+	if err != nil {
+		return dst, err
+	}
+	return collectOther(dst, base, subj, o.Other)
 }
 
-func labelType(dst []*rdf.Statement, about string, name xml.Name) []*rdf.Statement {
-	subj := mustTerm(rdf.NewIRITerm(about))
-	typ := mustTerm(rdf.NewIRITerm(name.Space + name.Local))
+func labelType(dst []*rdf.Statement, subj rdf.Term, name xml.Name) ([]*rdf.Statement, error) {
+	typ, err := iriTerm(name)
+	if err != nil {
+		return dst, err
+	}
 	s := &rdf.Statement{Subject: subj, Predicate: rdfType, Object: typ}
 	dst = append(dst, s)
-	return dst
+	return dst, nil
 }
 
 type rdfDataType struct {
@@ -566,7 +653,7 @@ func (r rdfDataType) claim() (pred, obj, qual string, kind rdf.Kind) {
 	}
 }
 
-func collect(dst []*rdf.Statement, about string, v interface{}) []*rdf.Statement {
+func collect(dst []*rdf.Statement, subj rdf.Term, v interface{}) ([]*rdf.Statement, error) {
 	rv := reflect.ValueOf(v)
 	for i := 0; i < rv.NumField(); i++ {
 		f := rv.Field(i)
@@ -579,22 +666,68 @@ func collect(dst []*rdf.Statement, about string, v interface{}) []*rdf.Statement
 		}
 		for _, e := range f.Interface().([]rdfDataType) {
 			claim, text, qual, kind := e.claim()
-			if kind != rdf.Invalid {
-				subj := mustTerm(rdf.NewIRITerm(about))
-				pred := mustTerm(rdf.NewIRITerm(claim))
-				var obj rdf.Term
-				switch kind {
-				case rdf.IRI:
-					obj = mustTerm(rdf.NewIRITerm(text))
-				case rdf.Literal:
-					obj = mustTerm(rdf.NewLiteralTerm(text, qual))
-				}
-				s := &rdf.Statement{Subject: subj, Predicate: pred, Object: obj}
-				dst = append(dst, s)
+			var err error
+			dst, err = appendClaim(dst, subj, claim, text, qual, kind)
+			if err != nil {
+				return dst, err
 			}
 		}
 	}
-	return dst
+	return dst, nil
+}
+
+// collectOther adds the statements for property elements of subj that are
+// not part of the OBO in OWL mapping using the generic RDF/XML conversion.
+// Relative IRIs in the elements are resolved against base.
+func collectOther(dst []*rdf.Statement, base string, subj rdf.Term, other []element) ([]*rdf.Statement, error) {
+	if len(other) == 0 {
+		return dst, nil
+	}
+	g := newGeneric(base, dst)
+	var li int
+	for i, p := range other {
+		err := g.property(subj, p, i, &li)
+		if err != nil {
+			return dst, err
+		}
+	}
+	return g.dst, nil
+}
+
+// appendClaim appends the statement claiming the object described by text,
+// qual and kind for subj with the predicate IRI pred. If kind is rdf.Invalid,
+// dst is returned unaltered.
+func appendClaim(dst []*rdf.Statement, subj rdf.Term, pred, text, qual string, kind rdf.Kind) ([]*rdf.Statement, error) {
+	if kind == rdf.Invalid {
+		return dst, nil
+	}
+	p, err := rdf.NewIRITerm(pred)
+	if err != nil {
+		return dst, err
+	}
+	obj, err := newTerm(text, qual, kind)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, &rdf.Statement{Subject: subj, Predicate: p, Object: obj}), nil
+}
+
+// newTerm returns an IRI or literal term for the given text, qualifier and
+// kind.
+func newTerm(text, qual string, kind rdf.Kind) (rdf.Term, error) {
+	switch kind {
+	case rdf.IRI:
+		return rdf.NewIRITerm(text)
+	case rdf.Literal:
+		return rdf.NewLiteralTerm(text, qual)
+	default:
+		return rdf.Term{}, fmt.Errorf("unexpected term kind: %s", kind)
+	}
+}
+
+// iriTerm returns an IRI term for the namespace qualified XML name.
+func iriTerm(name xml.Name) (rdf.Term, error) {
+	return rdf.NewIRITerm(name.Space + name.Local)
 }
 
 func blankLabel(h hash.Hash, parts ...string) string {
@@ -614,6 +747,8 @@ func hex(data []byte) string {
 	return string(buf)
 }
 
+// mustTerm returns t, panicking if err is not nil. It must only be used
+// for package-level terms.
 func mustTerm(t rdf.Term, err error) rdf.Term {
 	if err != nil {
 		panic(err)
@@ -0,0 +1,511 @@
+// Copyright ©2020 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package owl
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+)
+
+// Decoder is an OBO in OWL decoder. rdf.Statements returned by calls
+// to the Unmarshal and UnmarshalLocal methods have their Terms'
+// UID fields set so that unique terms will have unique IDs and so can be
+// used directly in a graph.Multi, or in a graph.Graph if all predicate
+// terms are identical. IDs created by the decoder all exist within a single
+// namespace and so Terms can be uniquely identified by their UID. Term
+// UIDs are based from 1 to allow RDF-aware client graphs to assign ID if
+// no ID has been assigned.
+type Decoder struct {
+	xml        *xml.Decoder
+	lines      *lineCounter
+	namespaces []xml.Attr
+	base       string
+
+	elements Element
+	strict   bool
+	filter   func(*rdf.Statement) bool
+
+	strings store
+	ids     map[string]int64
+
+	curr int
+	buf  []*rdf.Statement
+	seen map[[3]int64]bool
+}
+
+// Element is a set of top-level RDF/XML element kinds.
+type Element uint
+
+// Element kinds handled by the Decoder.
+const (
+	AnnotationProperty Element = 1 << iota // owl:AnnotationProperty
+	Axiom                                  // owl:Axiom
+	Class                                  // owl:Class
+	ObjectProperty                         // owl:ObjectProperty
+	Ontology                               // owl:Ontology
+
+	// Other is any top-level element that is not
+	// part of the OBO in OWL mapping. Statements for
+	// these elements are obtained by a generic
+	// RDF/XML conversion.
+	Other
+
+	// AllElements is the set of all element
+	// kinds handled by the Decoder.
+	AllElements = AnnotationProperty | Axiom | Class | ObjectProperty | Ontology | Other
+)
+
+// elementKinds maps XML local names to their element kind.
+var elementKinds = map[string]Element{
+	"AnnotationProperty": AnnotationProperty,
+	"Axiom":              Axiom,
+	"Class":              Class,
+	"ObjectProperty":     ObjectProperty,
+	"Ontology":           Ontology,
+}
+
+// Options holds Decoder options.
+type Options struct {
+	// Elements is the set of element kinds that
+	// statements are emitted for. Elements of other
+	// kinds are skipped. If Elements is zero, all
+	// element kinds are emitted.
+	Elements Element
+
+	// Strict specifies that top-level elements that
+	// are not part of the OBO in OWL mapping result
+	// in a SyntaxError with the ErrUnknownElement
+	// cause rather than being decoded as Other
+	// elements.
+	Strict bool
+
+	// Filter, if not nil, is called with each
+	// statement before it is interned, assigned
+	// term IDs and checked for uniqueness, and
+	// statements for which it returns false are
+	// dropped. The terms of statements passed to
+	// Filter hold full IRIs and have no UID.
+	Filter func(*rdf.Statement) bool
+}
+
+// NewDecoder returns a new Decoder that takes input from r and emits
+// statements for all element kinds.
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	return NewDecoderOptions(r, Options{})
+}
+
+// NewDecoderOptions returns a new Decoder that takes input from r using
+// the provided options.
+func NewDecoderOptions(r io.Reader, opts Options) (*Decoder, error) {
+	if opts.Elements == 0 {
+		opts.Elements = AllElements
+	}
+	lines := newLineCounter(r)
+	dec := &Decoder{
+		xml:      xml.NewDecoder(lines),
+		lines:    lines,
+		elements: opts.Elements,
+		strict:   opts.Strict,
+		filter:   opts.Filter,
+		strings:  make(store),
+		ids:      make(map[string]int64),
+		seen:     make(map[[3]int64]bool),
+	}
+	for dec.namespaces == nil {
+		err := dec.fillBuffer()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dec, nil
+}
+
+// Reset resets the decoder to use the provided io.Reader, retaining
+// the existing Term ID mapping and options. A new XML namespace is
+// obtained from the XML stream in r.
+func (dec *Decoder) Reset(r io.Reader) error {
+	dec.namespaces = nil
+	dec.base = ""
+	dec.lines = newLineCounter(r)
+	dec.xml = xml.NewDecoder(dec.lines)
+	for dec.namespaces == nil {
+		err := dec.fillBuffer()
+		if err != nil {
+			return err
+		}
+	}
+	for i := range dec.buf[dec.curr:] {
+		dec.buf[dec.curr+i] = nil
+	}
+	dec.curr = 0
+	dec.buf = dec.buf[:0]
+	dec.strings = make(store)
+	return nil
+}
+
+// Namespaces returns the namespaces collected from the XML stream. The
+// value returned by Namespaces is valid after the Decoder is returned
+// by NewDecoder or a successful Reset.
+func (dec *Decoder) Namespaces() []xml.Attr {
+	return dec.namespaces
+}
+
+// Unmarshal returns the next unique statement from the input stream that
+// is accepted by the decoder's filter.
+func (dec *Decoder) Unmarshal() (*rdf.Statement, error) {
+	for {
+		for len(dec.buf[dec.curr:]) == 0 {
+			err := dec.fillBuffer()
+			if err != nil {
+				return nil, err
+			}
+		}
+		s := dec.buf[dec.curr]
+		dec.buf[dec.curr] = nil
+		dec.curr++
+		if len(dec.buf[dec.curr:]) == 0 {
+			dec.curr = 0
+			dec.buf = dec.buf[:0]
+		}
+		if dec.filter != nil && !dec.filter(s) {
+			continue
+		}
+		s.Subject.Value = dec.strings.intern(s.Subject.Value)
+		s.Predicate.Value = dec.strings.intern(s.Predicate.Value)
+		s.Object.Value = dec.strings.intern(s.Object.Value)
+		s.Subject.UID = dec.idFor(s.Subject.Value)
+		s.Object.UID = dec.idFor(s.Object.Value)
+		s.Predicate.UID = dec.idFor(s.Predicate.Value)
+		triple := [3]int64{s.Subject.UID, s.Predicate.UID, s.Object.UID}
+		if !dec.seen[triple] {
+			dec.seen[triple] = true
+			return s, nil
+		}
+	}
+}
+
+// UnmarshalLocal returns the next unique statement from the input stream, but
+// replaces full IRI namespace text with the qualified name prefix obtained
+// from the decoders internal namespaces. The namespaces can be obtained by
+// using the Namespaces method.
+func (dec *Decoder) UnmarshalLocal() (*rdf.Statement, error) {
+	s, err := dec.Unmarshal()
+	if err != nil {
+		return nil, err
+	}
+	subj, err := dec.compactTerm(s.Subject)
+	if err != nil {
+		return s, err
+	}
+	s.Subject = subj
+	pred, err := dec.compactTerm(s.Predicate)
+	if err != nil {
+		return s, err
+	}
+	s.Predicate = pred
+	obj, err := dec.compactTerm(s.Object)
+	if err != nil {
+		return s, err
+	}
+	s.Object = obj
+	return s, nil
+}
+
+func (dec *Decoder) compactTerm(term rdf.Term) (rdf.Term, error) {
+	text, qual, kind, err := term.Parts()
+	if err != nil {
+		return term, err
+	}
+	uid := term.UID
+	switch kind {
+	case rdf.IRI:
+		new, changed := dec.compactIRI(text)
+		if changed {
+			term, err := rdf.NewIRITerm(new)
+			if err != nil {
+				return term, err
+			}
+			term.UID = uid
+			return term, nil
+		}
+	case rdf.Literal:
+		if qual == "" {
+			return term, nil
+		}
+		new, changed := dec.compactIRI(qual)
+		if changed {
+			term, err := rdf.NewLiteralTerm(text, new)
+			if err != nil {
+				return term, err
+			}
+			term.UID = uid
+			return term, nil
+		}
+	}
+	return term, nil
+}
+
+func (dec *Decoder) compactIRI(iri string) (new string, changed bool) {
+	// dec.namespaces is ordered longest to shortest
+	// to ensure prefixes are not eagerly chosen.
+	for _, ns := range dec.namespaces {
+		if strings.HasPrefix(iri, ns.Value) {
+			suffix := strings.TrimPrefix(iri, ns.Value)
+			if len(suffix) == 0 {
+				return iri, false
+			}
+			return ns.Name.Local + ":" + strings.TrimPrefix(iri, ns.Value), true
+		}
+	}
+	return iri, false
+}
+
+func (dec *Decoder) idFor(s string) int64 {
+	id, ok := dec.ids[s]
+	if ok {
+		return id
+	}
+	id = int64(len(dec.ids)) + 1
+	dec.ids[s] = id
+	return id
+}
+
+func (dec *Decoder) fillBuffer() error {
+	off, line := dec.xml.InputOffset(), dec.lines.line()
+	tok, err := dec.xml.Token()
+	if err != nil {
+		if err == io.EOF {
+			dec.strings = nil
+			return err
+		}
+		return &SyntaxError{Offset: off, Line: line, Err: err}
+	}
+	switch tok := tok.(type) {
+	case xml.StartElement:
+		err = dec.decodeElement(tok)
+		if err != nil {
+			return &SyntaxError{Offset: off, Line: line, Element: tok.Name, Err: err}
+		}
+
+	case xml.EndElement:
+	case xml.CharData:
+	case xml.Comment:
+	case xml.Directive:
+	case xml.ProcInst:
+	}
+	return nil
+}
+
+// decodeElement decodes the element starting with start, adding its
+// statements to the buffer. The buffer is not altered if an error is
+// returned.
+func (dec *Decoder) decodeElement(start xml.StartElement) error {
+	if kind, ok := elementKinds[start.Name.Local]; ok && kind&dec.elements == 0 {
+		return dec.xml.Skip()
+	}
+	buf := dec.buf
+	switch start.Name.Local {
+	case "AnnotationProperty":
+		var a annotationProperty
+		err := dec.xml.DecodeElement(&a, &start)
+		if err != nil {
+			return err
+		}
+		buf, err = a.collect(buf, dec.base)
+		if err != nil {
+			return err
+		}
+
+	case "Axiom":
+		var a axiom
+		err := dec.xml.DecodeElement(&a, &start)
+		if err != nil {
+			return err
+		}
+		buf, err = a.collect(buf, dec.base)
+		if err != nil {
+			return err
+		}
+
+	case "Class":
+		var c class
+		err := dec.xml.DecodeElement(&c, &start)
+		if err != nil {
+			return err
+		}
+		buf, err = c.collect(buf, dec.base)
+		if err != nil {
+			return err
+		}
+
+	case "ObjectProperty":
+		var o objectProperty
+		err := dec.xml.DecodeElement(&o, &start)
+		if err != nil {
+			return err
+		}
+		buf, err = o.collect(buf, dec.base)
+		if err != nil {
+			return err
+		}
+
+	case "Ontology":
+		var o ontology
+		err := dec.xml.DecodeElement(&o, &start)
+		if err != nil {
+			return err
+		}
+		buf, err = o.collect(buf, dec.base)
+		if err != nil {
+			return err
+		}
+
+	case "RDF":
+		dec.namespaces = make([]xml.Attr, 0, len(start.Attr))
+		for _, attr := range start.Attr {
+			if attr.Name.Space == xmlNS {
+				if attr.Name.Local == "base" {
+					dec.base = attr.Value
+				}
+				attr.Name.Space = "xml"
+			}
+			dec.namespaces = append(dec.namespaces, attr)
+		}
+		sort.Sort(byLength(dec.namespaces))
+
+	default:
+		if dec.strict {
+			return ErrUnknownElement
+		}
+		if dec.elements&Other == 0 {
+			return dec.xml.Skip()
+		}
+		var e element
+		err := dec.xml.DecodeElement(&e, &start)
+		if err != nil {
+			return err
+		}
+		g := newGeneric(dec.base, buf)
+		_, err = g.node(e)
+		if err != nil {
+			return err
+		}
+		buf = g.dst
+	}
+	dec.buf = buf
+	return nil
+}
+
+// ErrUnknownElement is the cause of a SyntaxError returned by a Decoder
+// in strict mode when a top-level element is not part of the OBO in OWL
+// mapping.
+var ErrUnknownElement = errors.New("unknown element")
+
+// SyntaxError is an error in the RDF/XML input of a Decoder.
+type SyntaxError struct {
+	// Offset and Line are the byte offset and
+	// the 1-based line number of the start of
+	// the token being decoded.
+	Offset int64
+	Line   int
+
+	// Element is the name of the top-level
+	// element being decoded. It is the zero
+	// xml.Name if the error occurred outside
+	// an element.
+	Element xml.Name
+
+	// Err is the underlying cause.
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Element == (xml.Name{}) {
+		return fmt.Sprintf("owl: line %d (offset %d): %v", e.Line, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("owl: line %d (offset %d): %s: %v", e.Line, e.Offset, qualified(e.Element), e.Err)
+}
+
+// Unwrap returns the underlying cause of the error.
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// lineCounter is an io.ByteReader that counts the lines it has read.
+// An xml.Decoder reads single bytes from an io.ByteReader rather than
+// buffering, so the count is in step with the decoder's input offset.
+type lineCounter struct {
+	r     io.ByteReader
+	lines int
+}
+
+func newLineCounter(r io.Reader) *lineCounter {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &lineCounter{r: br}
+}
+
+// line returns the 1-based line number of the next byte to be read.
+func (r *lineCounter) line() int {
+	return r.lines + 1
+}
+
+func (r *lineCounter) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil && b == '\n' {
+		r.lines++
+	}
+	return b, err
+}
+
+func (r *lineCounter) Read(p []byte) (int, error) {
+	for i := range p {
+		b, err := r.ReadByte()
+		if err != nil {
+			return i, err
+		}
+		p[i] = b
+	}
+	return len(p), nil
+}
+
+// qualified returns the XML name as a namespace qualified name.
+func qualified(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + name.Local
+}
+
+// store is a string internment implementation.
+type store map[string]string
+
+// intern returns an interned version of the parameter.
+func (is store) intern(s string) string {
+	if s == "" {
+		return ""
+	}
+	t, ok := is[s]
+	if ok {
+		return t
+	}
+	is[s] = s
+	return s
+}
+
+type byLength []xml.Attr
+
+func (a byLength) Len() int           { return len(a) }
+func (a byLength) Less(i, j int) bool { return len(a[i].Value) > len(a[j].Value) }
+func (a byLength) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,772 @@
+// Copyright ©2020 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package owl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/pkg/diff"
+	"github.com/pkg/diff/write"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+)
+
+// Test data obtained from http://geneontology.org/docs/download-ontology/
+// Gene Ontology Consortium data and data products are licensed under the
+// Creative Commons Attribution 4.0 Unported License.
+// https://creativecommons.org/licenses/by/4.0/legalcode
+//
+// N-Triple data sets were derived from the owl files with the following
+// python using the rdflib library available from https://github.com/RDFLib/rdflib/.
+//
+//  #!/usr/bin/python3
+//
+//  import rdflib
+//  import sys
+//
+//  g = rdflib.Graph()
+//  g.load(sys.argv[1])
+//
+//  print(g.serialize(format='nt').decode("utf-8"))
+//
+// The cl_subset data is an excerpt of the Cell Ontology obtained from
+// http://purl.obolibrary.org/obo/cl.owl, licensed under the Creative
+// Commons Attribution 4.0 Unported License, with the addition of elements
+// that are not part of the OBO in OWL mapping. Its N-Triple data set was
+// written by hand.
+
+func TestOwl(t *testing.T) {
+	tests, err := filepath.Glob("testdata/*.owl.gz")
+	if err != nil {
+		t.Fatalf("failed to get test data paths: %v", err)
+	}
+	for _, path := range tests {
+		name := strings.TrimSuffix(filepath.Base(path), ".gz")
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []*rdf.Statement
+		dec, err := NewDecoder(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			s, err := dec.Unmarshal()
+			if err != nil {
+				if err != io.EOF {
+					t.Errorf("error during decoding: %v", err)
+				}
+				break
+			}
+			got = append(got, s)
+		}
+		f.Close()
+
+		gotCan, err := rdf.URDNA2015(nil, got)
+		if err != nil {
+			t.Errorf("error during canonicalisation of %q: %v", name, err)
+		}
+
+		wantCan, err := canonicalFromNT(path)
+		if err != nil {
+			t.Errorf("error during golden data canonicalisation for %q: %v", name, err)
+		}
+
+		if !equalCanonicalGraphs(gotCan, wantCan) {
+			var got, want strings.Builder
+			for _, s := range gotCan {
+				fmt.Fprintln(&got, s)
+			}
+			for _, s := range wantCan {
+				fmt.Fprintln(&want, s)
+			}
+			var buf bytes.Buffer
+			err := diff.Text("got", "want", got.String(), want.String(), &buf, write.TerminalColor())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			t.Errorf("unexpected canonical graph for %q:\n%s", name, &buf)
+		}
+
+		gotNamespaces := dec.Namespaces()
+		wantNamespaces := namespacesFor(name)
+		if !reflect.DeepEqual(gotNamespaces, wantNamespaces) {
+			var got, want strings.Builder
+			for _, n := range gotNamespaces {
+				fmt.Fprintf(&got, "%+v\n", n)
+			}
+			for _, n := range wantNamespaces {
+				fmt.Fprintf(&want, "%+v\n", n)
+			}
+			var buf bytes.Buffer
+			err := diff.Text("got", "want", got.String(), want.String(), &buf, write.TerminalColor())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			t.Errorf("unexpected namespaces returned for %q:\n%s", name, &buf)
+		}
+	}
+}
+
+func canonicalFromNT(path string) ([]*rdf.Statement, error) {
+	path = strings.TrimSuffix(path, ".owl.gz") + ".nt.gz"
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []*rdf.Statement
+	dec := rdf.NewDecoder(r)
+	for {
+		s, err := dec.Unmarshal()
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		statements = append(statements, s)
+	}
+	f.Close()
+
+	return rdf.URDNA2015(nil, statements)
+}
+
+func equalCanonicalGraphs(a, b []*rdf.Statement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, ai := range a {
+		if ai.String() != b[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+func namespacesFor(file string) []xml.Attr {
+	if file == "cl_subset.owl" {
+		return clNamespaces
+	}
+	return []xml.Attr{
+		{
+			Name:  xml.Name{Space: "xmlns", Local: "terms"},
+			Value: "http://www.geneontology.org/formats/oboInOwl#http://purl.org/dc/terms/",
+		},
+		{
+			Name:  xml.Name{Space: "", Local: "xmlns"},
+			Value: "http://purl.obolibrary.org/obo/go/subsets/" + file + "#",
+		},
+		{
+			Name:  xml.Name{Space: "xml", Local: "base"},
+			Value: "http://purl.obolibrary.org/obo/go/subsets/" + file,
+		},
+		{
+			Name:  xml.Name{Space: "xmlns", Local: "oboInOwl"},
+			Value: "http://www.geneontology.org/formats/oboInOwl#",
+		},
+		{
+			Name:  xml.Name{Space: "xmlns", Local: "rdf"},
+			Value: "http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+		},
+		{
+			Name:  xml.Name{Space: "xmlns", Local: "rdfs"},
+			Value: "http://www.w3.org/2000/01/rdf-schema#",
+		},
+		{
+			Name:  xml.Name{Space: "xmlns", Local: "xml"},
+			Value: "http://www.w3.org/XML/1998/namespace",
+		},
+		{
+			Name:  xml.Name{Space: "xmlns", Local: "go"},
+			Value: "http://purl.obolibrary.org/obo/go#",
+		},
+		{
+			Name:  xml.Name{Space: "xmlns", Local: "xsd"},
+			Value: "http://www.w3.org/2001/XMLSchema#",
+		},
+		{
+			Name:  xml.Name{Space: "xmlns", Local: "obo"},
+			Value: "http://purl.obolibrary.org/obo/",
+		},
+		{
+			Name:  xml.Name{Space: "xmlns", Local: "owl"},
+			Value: "http://www.w3.org/2002/07/owl#",
+		},
+	}
+}
+
+var clNamespaces = []xml.Attr{
+	{
+		Name:  xml.Name{Space: "xmlns", Local: "oboInOwl"},
+		Value: "http://www.geneontology.org/formats/oboInOwl#",
+	},
+	{
+		Name:  xml.Name{Space: "xmlns", Local: "rdf"},
+		Value: "http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+	},
+	{
+		Name:  xml.Name{Space: "", Local: "xmlns"},
+		Value: "http://purl.obolibrary.org/obo/cl.owl#",
+	},
+	{
+		Name:  xml.Name{Space: "xml", Local: "base"},
+		Value: "http://purl.obolibrary.org/obo/cl.owl",
+	},
+	{
+		Name:  xml.Name{Space: "xmlns", Local: "rdfs"},
+		Value: "http://www.w3.org/2000/01/rdf-schema#",
+	},
+	{
+		Name:  xml.Name{Space: "xmlns", Local: "xml"},
+		Value: "http://www.w3.org/XML/1998/namespace",
+	},
+	{
+		Name:  xml.Name{Space: "xmlns", Local: "xsd"},
+		Value: "http://www.w3.org/2001/XMLSchema#",
+	},
+	{
+		Name:  xml.Name{Space: "xmlns", Local: "obo"},
+		Value: "http://purl.obolibrary.org/obo/",
+	},
+	{
+		Name:  xml.Name{Space: "xmlns", Local: "owl"},
+		Value: "http://www.w3.org/2002/07/owl#",
+	},
+}
+
+func TestElements(t *testing.T) {
+	const path = "testdata/cl_subset.owl.gz"
+
+	all, err := decodeFile(path, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error decoding %q: %v", path, err)
+	}
+
+	// Statements are only emitted for the requested element kinds,
+	// and the statements of each kind together make up the complete
+	// set of statements.
+	kinds := []struct {
+		kind  Element
+		types []string
+	}{
+		{kind: AnnotationProperty, types: []string{"<http://www.w3.org/2002/07/owl#AnnotationProperty>"}},
+		{kind: Axiom, types: []string{"<http://www.w3.org/2002/07/owl#Axiom>"}},
+		{kind: Class, types: []string{"<http://www.w3.org/2002/07/owl#Class>", "<http://www.w3.org/2002/07/owl#Restriction>"}},
+		{kind: ObjectProperty, types: []string{"<http://www.w3.org/2002/07/owl#ObjectProperty>"}},
+		{kind: Ontology, types: []string{"<http://www.w3.org/2002/07/owl#Ontology>"}},
+		{kind: Other, types: []string{"<http://www.w3.org/2002/07/owl#NamedIndividual>"}},
+	}
+	var union []*rdf.Statement
+	for _, test := range kinds {
+		got, err := decodeFile(path, Options{Elements: test.kind})
+		if err != nil {
+			t.Fatalf("unexpected error decoding %q with elements %b: %v", path, test.kind, err)
+		}
+		if len(got) == 0 {
+			t.Errorf("no statements for elements %b", test.kind)
+		}
+		for _, s := range got {
+			if s.Predicate.Value != rdfType.Value {
+				continue
+			}
+			var ok bool
+			for _, typ := range test.types {
+				if s.Object.Value == typ {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				t.Errorf("unexpected type statement for elements %b: %s", test.kind, s)
+			}
+		}
+		union = append(union, got...)
+	}
+
+	gotCan, err := rdf.URDNA2015(nil, union)
+	if err != nil {
+		t.Fatalf("error during canonicalisation: %v", err)
+	}
+	wantCan, err := rdf.URDNA2015(nil, all)
+	if err != nil {
+		t.Fatalf("error during canonicalisation: %v", err)
+	}
+	if !equalCanonicalGraphs(gotCan, wantCan) {
+		t.Errorf("union of element kinds does not match all elements:\ngot: %d statements\nwant:%d statements", len(gotCan), len(wantCan))
+	}
+
+	got, err := decodeFile(path, Options{Elements: Class | Axiom})
+	if err != nil {
+		t.Fatalf("unexpected error decoding %q: %v", path, err)
+	}
+	for _, s := range got {
+		if s.Predicate.Value == rdfType.Value {
+			switch s.Object.Value {
+			case "<http://www.w3.org/2002/07/owl#Class>", "<http://www.w3.org/2002/07/owl#Restriction>", "<http://www.w3.org/2002/07/owl#Axiom>":
+			default:
+				t.Errorf("unexpected type statement for elements %b: %s", Class|Axiom, s)
+			}
+		}
+	}
+}
+
+var genericTests = []struct {
+	name string
+	doc  string
+	want string
+}{
+	{
+		name: "elements",
+		doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:obo="http://purl.obolibrary.org/obo/"
+     xml:base="http://purl.obolibrary.org/obo/test.owl"
+     xmlns:ex="http://example.org/"
+     xmlns:owl="http://www.w3.org/2002/07/owl#"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+     xmlns:rdfs="http://www.w3.org/2000/01/rdf-schema#">
+    <owl:NamedIndividual rdf:about="http://purl.obolibrary.org/obo/IAO_0000125">
+        <rdfs:label xml:lang="en">pending final vetting</rdfs:label>
+    </owl:NamedIndividual>
+    <owl:DatatypeProperty rdf:ID="prop" ex:note="note"/>
+    <rdf:Description rdf:about="http://purl.obolibrary.org/obo/CL_0000000">
+        <rdf:type rdf:resource="http://www.w3.org/2002/07/owl#Class"/>
+        <ex:size rdf:datatype="http://www.w3.org/2001/XMLSchema#integer">10</ex:size>
+        <ex:part rdf:nodeID="n1"/>
+        <ex:detail rdf:parseType="Resource">
+            <ex:value>one</ex:value>
+        </ex:detail>
+        <ex:members rdf:parseType="Collection">
+            <rdf:Description rdf:about="http://example.org/a"/>
+            <rdf:Description rdf:about="http://example.org/b"/>
+        </ex:members>
+    </rdf:Description>
+    <rdf:Bag rdf:nodeID="n1">
+        <rdf:li>first</rdf:li>
+        <rdf:li rdf:resource="http://example.org/second"/>
+    </rdf:Bag>
+    <owl:Class rdf:about="http://purl.obolibrary.org/obo/CL_0000001">
+        <obo:RO_0002175 rdf:resource="http://purl.obolibrary.org/obo/NCBITaxon_9606"/>
+    </owl:Class>
+</rdf:RDF>
+`,
+		want: `<http://purl.obolibrary.org/obo/IAO_0000125> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#NamedIndividual> .
+<http://purl.obolibrary.org/obo/IAO_0000125> <http://www.w3.org/2000/01/rdf-schema#label> "pending final vetting"@en .
+<http://purl.obolibrary.org/obo/test.owl#prop> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#DatatypeProperty> .
+<http://purl.obolibrary.org/obo/test.owl#prop> <http://example.org/note> "note" .
+<http://purl.obolibrary.org/obo/CL_0000000> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#Class> .
+<http://purl.obolibrary.org/obo/CL_0000000> <http://example.org/size> "10"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://purl.obolibrary.org/obo/CL_0000000> <http://example.org/part> _:n1 .
+<http://purl.obolibrary.org/obo/CL_0000000> <http://example.org/detail> _:d .
+_:d <http://example.org/value> "one" .
+<http://purl.obolibrary.org/obo/CL_0000000> <http://example.org/members> _:l1 .
+_:l1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/a> .
+_:l1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:l2 .
+_:l2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/b> .
+_:l2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+_:n1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/1999/02/22-rdf-syntax-ns#Bag> .
+_:n1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#_1> "first" .
+_:n1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#_2> <http://example.org/second> .
+<http://purl.obolibrary.org/obo/CL_0000001> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#Class> .
+<http://purl.obolibrary.org/obo/CL_0000001> <http://purl.obolibrary.org/obo/RO_0002175> <http://purl.obolibrary.org/obo/NCBITaxon_9606> .
+`,
+	},
+	{
+		name: "node attributes",
+		doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:ex="http://example.org/"
+     xmlns:owl="http://www.w3.org/2002/07/owl#"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <rdf:Description rdf:about="http://example.org/a" rdf:type="http://www.w3.org/2002/07/owl#Thing" ex:note="note"/>
+    <owl:NamedIndividual rdf:about="http://example.org/b" rdf:type="http://example.org/Kind" xml:lang="en" ex:note="note"/>
+</rdf:RDF>
+`,
+		want: `<http://example.org/a> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#Thing> .
+<http://example.org/a> <http://example.org/note> "note" .
+<http://example.org/b> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#NamedIndividual> .
+<http://example.org/b> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Kind> .
+<http://example.org/b> <http://example.org/note> "note"@en .
+`,
+	},
+	{
+		name: "property attributes",
+		doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:ex="http://example.org/"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <rdf:Description rdf:about="http://example.org/a">
+        <ex:typed rdf:type="http://example.org/Kind"/>
+        <ex:attributed ex:value="one"/>
+        <ex:both rdf:type="http://example.org/Kind" ex:value="two"/>
+        <ex:linked rdf:resource="http://example.org/b" ex:value="three"/>
+    </rdf:Description>
+</rdf:RDF>
+`,
+		want: `<http://example.org/a> <http://example.org/typed> _:t .
+_:t <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Kind> .
+<http://example.org/a> <http://example.org/attributed> _:a .
+_:a <http://example.org/value> "one" .
+<http://example.org/a> <http://example.org/both> _:b .
+_:b <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Kind> .
+_:b <http://example.org/value> "two" .
+<http://example.org/a> <http://example.org/linked> <http://example.org/b> .
+<http://example.org/b> <http://example.org/value> "three" .
+`,
+	},
+	{
+		name: "relative resource in class",
+		doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:ex="http://example.org/"
+     xml:base="http://purl.obolibrary.org/obo/test.owl"
+     xmlns:owl="http://www.w3.org/2002/07/owl#"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <owl:Class rdf:about="http://purl.obolibrary.org/obo/CL_0000001">
+        <ex:seeAlso rdf:resource="#CL_0000002"/>
+        <ex:detail rdf:parseType="Resource">
+            <ex:source rdf:resource="CL_0000003"/>
+        </ex:detail>
+    </owl:Class>
+</rdf:RDF>
+`,
+		want: `<http://purl.obolibrary.org/obo/CL_0000001> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#Class> .
+<http://purl.obolibrary.org/obo/CL_0000001> <http://example.org/seeAlso> <http://purl.obolibrary.org/obo/test.owl#CL_0000002> .
+<http://purl.obolibrary.org/obo/CL_0000001> <http://example.org/detail> _:d .
+_:d <http://example.org/source> <http://purl.obolibrary.org/obo/CL_0000003> .
+`,
+	},
+}
+
+func TestGeneric(t *testing.T) {
+	for _, test := range genericTests {
+		dec, err := NewDecoder(strings.NewReader(test.doc))
+		if err != nil {
+			t.Fatalf("unexpected error creating decoder for %q: %v", test.name, err)
+		}
+		var got []*rdf.Statement
+		for {
+			s, err := dec.Unmarshal()
+			if err != nil {
+				if err != io.EOF {
+					t.Fatalf("unexpected error decoding %q: %v", test.name, err)
+				}
+				break
+			}
+			got = append(got, s)
+		}
+		gotCan, err := rdf.URDNA2015(nil, got)
+		if err != nil {
+			t.Fatalf("error during canonicalisation of %q: %v", test.name, err)
+		}
+
+		var wantStatements []*rdf.Statement
+		wantDec := rdf.NewDecoder(strings.NewReader(test.want))
+		for {
+			s, err := wantDec.Unmarshal()
+			if err != nil {
+				if err != io.EOF {
+					t.Fatalf("unexpected error decoding golden statements for %q: %v", test.name, err)
+				}
+				break
+			}
+			wantStatements = append(wantStatements, s)
+		}
+		wantCan, err := rdf.URDNA2015(nil, wantStatements)
+		if err != nil {
+			t.Fatalf("error during golden data canonicalisation for %q: %v", test.name, err)
+		}
+
+		if !equalCanonicalGraphs(gotCan, wantCan) {
+			var got, want strings.Builder
+			for _, s := range gotCan {
+				fmt.Fprintln(&got, s)
+			}
+			for _, s := range wantCan {
+				fmt.Fprintln(&want, s)
+			}
+			t.Errorf("unexpected canonical graph for %q:\ngot:\n%s\nwant:\n%s", test.name, &got, &want)
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	const path = "testdata/cl_subset.owl.gz"
+
+	_, strictErr := decodeFile(path, Options{Strict: true})
+	if strictErr == nil {
+		t.Fatal("expected error decoding unknown element in strict mode")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := bytes.Index(doc, []byte("<owl:NamedIndividual"))
+	want := &SyntaxError{
+		Offset:  int64(offset),
+		Line:    bytes.Count(doc[:offset], []byte("\n")) + 1,
+		Element: xml.Name{Space: "http://www.w3.org/2002/07/owl#", Local: "NamedIndividual"},
+		Err:     ErrUnknownElement,
+	}
+	var got *SyntaxError
+	if !errors.As(strictErr, &got) {
+		t.Fatalf("unexpected error type: %T", strictErr)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected error: got:%#v want:%#v", got, want)
+	}
+	if !errors.Is(strictErr, ErrUnknownElement) {
+		t.Errorf("expected error to wrap ErrUnknownElement: %v", strictErr)
+	}
+
+	// Excluded elements are skipped, even in strict mode.
+	_, err = decodeFile("testdata/goslim_generic.owl.gz", Options{Elements: Class, Strict: true})
+	if err != nil {
+		t.Errorf("unexpected error decoding in strict mode: %v", err)
+	}
+}
+
+var syntaxErrorTests = []struct {
+	name    string
+	doc     string
+	line    int
+	element xml.Name
+}{
+	{
+		name: "relative IRI",
+		doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:owl="http://www.w3.org/2002/07/owl#"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <owl:Class rdf:about="http://purl.obolibrary.org/obo/CL_0000000"/>
+    <owl:Class rdf:about="CL_0000001"/>
+</rdf:RDF>
+`,
+		line:    5,
+		element: xml.Name{Space: "http://www.w3.org/2002/07/owl#", Local: "Class"},
+	},
+	{
+		name: "relative IRI in generic element",
+		doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:owl="http://www.w3.org/2002/07/owl#"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+
+    <owl:NamedIndividual rdf:about="IAO_0000125"/>
+</rdf:RDF>
+`,
+		line:    5,
+		element: xml.Name{Space: "http://www.w3.org/2002/07/owl#", Local: "NamedIndividual"},
+	},
+	{
+		name: "missing annotated target",
+		doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:owl="http://www.w3.org/2002/07/owl#"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <owl:Axiom>
+        <owl:annotatedSource rdf:resource="http://purl.obolibrary.org/obo/CL_0000540"/>
+        <owl:annotatedProperty rdf:resource="http://purl.obolibrary.org/obo/IAO_0000115"/>
+    </owl:Axiom>
+</rdf:RDF>
+`,
+		line:    4,
+		element: xml.Name{Space: "http://www.w3.org/2002/07/owl#", Local: "Axiom"},
+	},
+	{
+		name: "mismatched element",
+		doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:owl="http://www.w3.org/2002/07/owl#"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <owl:Class rdf:about="http://purl.obolibrary.org/obo/CL_0000000">
+    </owl:Ontology>
+</rdf:RDF>
+`,
+		line:    4,
+		element: xml.Name{Space: "http://www.w3.org/2002/07/owl#", Local: "Class"},
+	},
+	{
+		name: "truncated",
+		doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:owl="http://www.w3.org/2002/07/owl#"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <owl:Class rdf:about="http://purl.obolibrary.org/obo/CL_0000000"/>
+`,
+		line: 5,
+	},
+}
+
+func TestSyntaxError(t *testing.T) {
+	for _, test := range syntaxErrorTests {
+		dec, err := NewDecoder(strings.NewReader(test.doc))
+		if err != nil {
+			t.Errorf("unexpected error creating decoder for %s: %v", test.name, err)
+			continue
+		}
+		for {
+			_, err = dec.Unmarshal()
+			if err != nil {
+				break
+			}
+		}
+		var got *SyntaxError
+		if !errors.As(err, &got) {
+			t.Errorf("unexpected error for %s: %v", test.name, err)
+			continue
+		}
+		if got.Line != test.line {
+			t.Errorf("unexpected line for %s: got:%d want:%d", test.name, got.Line, test.line)
+		}
+		if got.Element != test.element {
+			t.Errorf("unexpected element for %s: got:%v want:%v", test.name, got.Element, test.element)
+		}
+		lines := strings.Split(test.doc, "\n")
+		wantOffset := int64(len(strings.Join(lines[:test.line-1], "\n")) + 1)
+		if got.Element != (xml.Name{}) {
+			wantOffset += int64(strings.Index(lines[test.line-1], "<"))
+		}
+		if got.Offset != wantOffset {
+			t.Errorf("unexpected offset for %s: got:%d want:%d", test.name, got.Offset, wantOffset)
+		}
+		if got.Err == nil {
+			t.Errorf("missing cause for %s", test.name)
+		}
+	}
+}
+
+var goOWL = flag.String("go.owl", "testdata/goslim_generic.owl.gz", "gzip compressed OBO in OWL file used for benchmarks, for example go.owl.gz")
+
+// leanFilter retains the statements needed to paint a Gene Ontology DAG.
+func leanFilter(s *rdf.Statement) bool {
+	switch s.Predicate.Value {
+	case "<http://www.w3.org/2000/01/rdf-schema#subClassOf>",
+		"<http://www.geneontology.org/formats/oboInOwl#hasOBONamespace>",
+		"<http://www.w3.org/2002/07/owl#onProperty>",
+		"<http://www.w3.org/2002/07/owl#someValuesFrom>":
+		return true
+	}
+	return false
+}
+
+// BenchmarkDecode reports the heap retained by a Decoder after decoding
+// the ontology at the path given by the -go.owl flag. The full Gene Ontology
+// can be obtained from http://current.geneontology.org/ontology/go.owl.
+func BenchmarkDecode(b *testing.B) {
+	f, err := os.Open(*goOWL)
+	if err != nil {
+		b.Fatalf("failed to open ontology: %v", err)
+	}
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		b.Fatalf("failed to open ontology: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	f.Close()
+	if err != nil {
+		b.Fatalf("failed to read ontology: %v", err)
+	}
+
+	for _, bench := range []struct {
+		name string
+		opts Options
+	}{
+		{name: "all"},
+		{name: "filter", opts: Options{Filter: leanFilter}},
+		{name: "filter_elements", opts: Options{Elements: AllElements &^ (Axiom | Ontology), Filter: leanFilter}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var retained int64
+			var statements int
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				dec, err := NewDecoderOptions(bytes.NewReader(data), bench.opts)
+				if err != nil {
+					b.Fatalf("unexpected error creating decoder: %v", err)
+				}
+				statements = 0
+				for {
+					_, err := dec.Unmarshal()
+					if err != nil {
+						if err != io.EOF {
+							b.Fatalf("unexpected error decoding: %v", err)
+						}
+						break
+					}
+					statements++
+				}
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				runtime.KeepAlive(dec)
+				retained = int64(after.HeapAlloc) - int64(before.HeapAlloc)
+			}
+			b.ReportMetric(float64(retained), "retained-B")
+			b.ReportMetric(float64(statements), "statements")
+		})
+	}
+}
+
+func decodeFile(path string, opts Options) ([]*rdf.Statement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := NewDecoderOptions(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	var statements []*rdf.Statement
+	for {
+		s, err := dec.Unmarshal()
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		statements = append(statements, s)
+	}
+	return statements, nil
+}
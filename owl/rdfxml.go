@@ -0,0 +1,321 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package owl
+
+import (
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+)
+
+// This file contains a generic RDF/XML conversion used for elements that
+// are not part of the OBO in OWL mapping handled in model.go.
+//
+// For the RDF/XML grammar, see:
+// https://www.w3.org/TR/rdf-syntax-grammar/.
+
+const (
+	rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlNS = "http://www.w3.org/XML/1998/namespace"
+)
+
+// element is a generic RDF/XML node or property element.
+type element struct {
+	XMLName xml.Name
+
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Inner    string     `xml:",innerxml"`
+	Children []element  `xml:",any"`
+}
+
+// attr returns the value of the RDF syntax attribute with the given local
+// name. Unqualified attributes are accepted for compatibility with the
+// OBO in OWL mapping.
+func (e *element) attr(local string) (string, bool) {
+	for _, a := range e.Attrs {
+		if a.Name.Local == local && (a.Name.Space == rdfNS || a.Name.Space == "") {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// lang returns the xml:lang of the element.
+func (e *element) lang() string {
+	for _, a := range e.Attrs {
+		if a.Name.Space == xmlNS && a.Name.Local == "lang" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// propertyAttrs returns the attributes of the element that are property
+// attributes.
+func (e *element) propertyAttrs() []xml.Attr {
+	var attrs []xml.Attr
+	for _, a := range e.Attrs {
+		switch {
+		case a.Name.Space == "xmlns", a.Name.Space == "" && a.Name.Local == "xmlns":
+			// Namespace declaration.
+		case a.Name.Space == xmlNS, a.Name.Space == "":
+			// XML attribute or unqualified RDF syntax attribute.
+		case a.Name.Space == rdfNS:
+			switch a.Name.Local {
+			case "about", "ID", "nodeID", "resource", "datatype", "parseType", "type", "bagID", "aboutEach", "aboutEachPrefix":
+				// RDF syntax attribute or rdf:type, which
+				// is handled separately as an IRI.
+			default:
+				attrs = append(attrs, a)
+			}
+		default:
+			attrs = append(attrs, a)
+		}
+	}
+	return attrs
+}
+
+// generic converts generic RDF/XML elements to statements.
+type generic struct {
+	// base is the xml:base of the document.
+	base string
+
+	h   hash.Hash
+	dst []*rdf.Statement
+}
+
+func newGeneric(base string, dst []*rdf.Statement) *generic {
+	return &generic{base: base, h: md5.New(), dst: dst}
+}
+
+// node adds the statements described by the node element e and returns
+// the subject of the node. If the node has no IRI or node ID, the subject
+// is a blank node labelled with a label derived from parts and the content
+// of the node.
+func (g *generic) node(e element, parts ...string) (rdf.Term, error) {
+	subj, err := g.subject(e, parts...)
+	if err != nil {
+		return subj, err
+	}
+	if e.XMLName.Space != rdfNS || e.XMLName.Local != "Description" {
+		typ, err := rdf.NewIRITerm(e.XMLName.Space + e.XMLName.Local)
+		if err != nil {
+			return subj, err
+		}
+		g.add(subj, rdfType, typ)
+	}
+	err = g.propertyAttrs(subj, e)
+	if err != nil {
+		return subj, err
+	}
+	var li int
+	for i, p := range e.Children {
+		err = g.property(subj, p, i, &li)
+		if err != nil {
+			return subj, err
+		}
+	}
+	return subj, nil
+}
+
+// subject returns the subject of the node element e.
+func (g *generic) subject(e element, parts ...string) (rdf.Term, error) {
+	if about, ok := e.attr("about"); ok {
+		return g.iri(about)
+	}
+	if id, ok := e.attr("ID"); ok {
+		return g.iri("#" + id)
+	}
+	if id, ok := e.attr("nodeID"); ok {
+		return rdf.NewBlankTerm(blankLabel(g.h, "nodeID", id))
+	}
+	parts = append(parts, e.XMLName.Space, e.XMLName.Local, e.Inner)
+	return rdf.NewBlankTerm(blankLabel(g.h, parts...))
+}
+
+// propertyAttrs adds a plain literal statement for each of the property
+// attributes of e with subj as the subject, and an rdf:type statement if e
+// has an rdf:type attribute.
+func (g *generic) propertyAttrs(subj rdf.Term, e element) error {
+	if typ, ok := e.attr("type"); ok {
+		obj, err := g.iri(typ)
+		if err != nil {
+			return err
+		}
+		g.add(subj, rdfType, obj)
+	}
+	for _, a := range e.propertyAttrs() {
+		pred, err := rdf.NewIRITerm(a.Name.Space + a.Name.Local)
+		if err != nil {
+			return err
+		}
+		obj, err := rdf.NewLiteralTerm(a.Value, langQual(e.lang()))
+		if err != nil {
+			return err
+		}
+		g.add(subj, pred, obj)
+	}
+	return nil
+}
+
+// property adds the statements described by the property element p of
+// a node with the subject subj. The index of the property in its node is
+// i and li is the counter for rdf:li container membership properties.
+func (g *generic) property(subj rdf.Term, p element, i int, li *int) error {
+	name := p.XMLName.Space + p.XMLName.Local
+	if p.XMLName.Space == rdfNS && p.XMLName.Local == "li" {
+		*li++
+		name = rdfNS + "_" + strconv.Itoa(*li)
+	}
+	pred, err := rdf.NewIRITerm(name)
+	if err != nil {
+		return err
+	}
+
+	parseType, _ := p.attr("parseType")
+	switch parseType {
+	case "":
+	case "Resource":
+		obj, err := rdf.NewBlankTerm(blankLabel(g.h, subj.Value, name, strconv.Itoa(i), p.Inner))
+		if err != nil {
+			return err
+		}
+		g.add(subj, pred, obj)
+		var li int
+		for j, c := range p.Children {
+			err = g.property(obj, c, j, &li)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "Collection":
+		return g.collection(subj, pred, p, i)
+	default:
+		// rdf:parseType="Literal" and unknown parse
+		// types are treated as XML literals.
+		obj, err := rdf.NewLiteralTerm(p.Inner, rdfNS+"XMLLiteral")
+		if err != nil {
+			return err
+		}
+		g.add(subj, pred, obj)
+		return nil
+	}
+
+	var obj rdf.Term
+	resource, isResource := p.attr("resource")
+	nodeID, isNode := p.attr("nodeID")
+	_, isTyped := p.attr("type")
+	attrs := p.propertyAttrs()
+	switch {
+	case len(p.Children) != 0:
+		obj, err = g.node(p.Children[0], subj.Value, name, strconv.Itoa(i))
+	case isResource:
+		obj, err = g.iri(resource)
+	case isNode:
+		obj, err = rdf.NewBlankTerm(blankLabel(g.h, "nodeID", nodeID))
+	case isTyped, len(attrs) != 0:
+		obj, err = rdf.NewBlankTerm(blankLabel(g.h, subj.Value, name, strconv.Itoa(i), p.Inner))
+	default:
+		qual, ok := p.attr("datatype")
+		if ok {
+			qual, err = g.resolve(qual)
+			if err != nil {
+				return err
+			}
+		} else {
+			qual = langQual(p.lang())
+		}
+		obj, err = rdf.NewLiteralTerm(p.Text, qual)
+	}
+	if err != nil {
+		return err
+	}
+	g.add(subj, pred, obj)
+	if len(p.Children) == 0 {
+		return g.propertyAttrs(obj, p)
+	}
+	return nil
+}
+
+// collection adds the statements for the rdf:parseType="Collection"
+// property element p of a node with the subject subj.
+func (g *generic) collection(subj, pred rdf.Term, p element, i int) error {
+	if len(p.Children) == 0 {
+		g.add(subj, pred, rdfNil)
+		return nil
+	}
+	label := blankLabel(g.h, subj.Value, pred.Value, strconv.Itoa(i), p.Inner)
+	blank, err := rdf.NewBlankTerm(label)
+	if err != nil {
+		return err
+	}
+	g.add(subj, pred, blank)
+	for j, c := range p.Children {
+		obj, err := g.node(c, label, strconv.Itoa(j))
+		if err != nil {
+			return err
+		}
+		g.add(blank, rdfFirst, obj)
+		if j < len(p.Children)-1 {
+			label = blankLabel(g.h, label)
+			next, err := rdf.NewBlankTerm(label)
+			if err != nil {
+				return err
+			}
+			g.add(blank, rdfRest, next)
+			blank = next
+		} else {
+			g.add(blank, rdfRest, rdfNil)
+		}
+	}
+	return nil
+}
+
+// iri returns an IRI term for ref resolved against the document base.
+func (g *generic) iri(ref string) (rdf.Term, error) {
+	iri, err := g.resolve(ref)
+	if err != nil {
+		return rdf.Term{}, err
+	}
+	return rdf.NewIRITerm(iri)
+}
+
+// resolve returns ref resolved against the document base.
+func (g *generic) resolve(ref string) (string, error) {
+	if g.base == "" {
+		return ref, nil
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if u.IsAbs() {
+		return ref, nil
+	}
+	base, err := url.Parse(g.base)
+	if err != nil {
+		return "", fmt.Errorf("invalid xml:base: %w", err)
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+func (g *generic) add(subj, pred, obj rdf.Term) {
+	g.dst = append(g.dst, &rdf.Statement{Subject: subj, Predicate: pred, Object: obj})
+}
+
+// langQual returns the literal qualifier for the language tag lang.
+func langQual(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	return "@" + lang
+}
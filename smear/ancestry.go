@@ -0,0 +1,84 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/formats/rdf"
+	"gonum.org/v1/gonum/graph/traverse"
+
+	"github.com/kortschak/gogo"
+)
+
+// ancestry is a memoised index of the GO ancestors of the terms in an
+// ontology with respect to a set of relations. The ancestor closure of
+// each term is calculated on first use and shared by all later queries,
+// so the DAG is walked at most once from each term rather than once for
+// each gene annotated to it. It is safe for concurrent use.
+type ancestry struct {
+	g    *gogo.Graph
+	rels Relations
+
+	mu        sync.Mutex
+	ancestors map[int64][]ancestor
+}
+
+// ancestor is a member of the ancestor closure of a term.
+type ancestor struct {
+	term rdf.Term
+
+	// depth is the number of levels
+	// separating the ancestor from the
+	// term on the shortest path.
+	depth int
+}
+
+// newAncestry returns a new ancestry index for g following the relations
+// in rels.
+func newAncestry(g *gogo.Graph, rels Relations) *ancestry {
+	return &ancestry{g: g, rels: rels, ancestors: make(map[int64][]ancestor)}
+}
+
+// of returns the ancestor closure of t, including t itself at depth zero,
+// sorted by term ID. The returned slice must not be mutated.
+func (a *ancestry) of(t rdf.Term) []ancestor {
+	a.mu.Lock()
+	anc, ok := a.ancestors[t.ID()]
+	a.mu.Unlock()
+	if ok {
+		return anc
+	}
+
+	bf := traverse.BreadthFirst{Traverse: a.rels.isSubClassOfGO}
+	bf.Walk(a.g, t, func(n graph.Node, d int) bool {
+		anc = append(anc, ancestor{term: n.(rdf.Term), depth: d})
+		return false
+	})
+	sort.Slice(anc, func(i, j int) bool { return anc[i].term.ID() < anc[j].term.ID() })
+
+	a.mu.Lock()
+	a.ancestors[t.ID()] = anc
+	a.mu.Unlock()
+	return anc
+}
+
+// isDescendantOf returns whether the query q is a descendant of t via the
+// relations of the index and how many levels separate them if it is. If q
+// is not a descendant of t, depth will be negative.
+func (a *ancestry) isDescendantOf(t, q rdf.Term) (yes bool, depth int) {
+	if !strings.HasPrefix(t.Value, "<obo:GO_") || !strings.HasPrefix(q.Value, "<obo:GO_") {
+		return false, -1
+	}
+	anc := a.of(q)
+	i := sort.Search(len(anc), func(i int) bool { return anc[i].term.ID() >= t.ID() })
+	if i == len(anc) || anc[i].term.ID() != t.ID() {
+		return false, -1
+	}
+	return true, anc[i].depth
+}
@@ -0,0 +1,207 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/formats/rdf"
+	"gonum.org/v1/gonum/graph/traverse"
+
+	"github.com/kortschak/gogo"
+)
+
+const testAncestryOBO = `format-version: 1.4
+ontology: go
+
+[Term]
+id: GO:0008150
+name: biological_process
+namespace: biological_process
+
+[Term]
+id: GO:0000010
+name: process
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+
+[Term]
+id: GO:0000011
+name: subprocess
+namespace: biological_process
+is_a: GO:0000010 ! process
+
+[Term]
+id: GO:0000012
+name: subprocess part
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+relationship: part_of GO:0000011 ! subprocess
+
+[Term]
+id: GO:0000013
+name: regulation of subprocess part
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+relationship: regulates GO:0000012 ! subprocess part
+
+[Term]
+id: GO:0000014
+name: specific regulation of subprocess part
+namespace: biological_process
+is_a: GO:0000013 ! regulation of subprocess part
+relationship: part_of GO:0000010 ! process
+
+[Typedef]
+id: part_of
+name: part of
+xref: BFO:0000050
+
+[Typedef]
+id: regulates
+name: regulates
+xref: RO:0002211
+`
+
+var ancestryTests = []struct {
+	rels      string
+	t, q      string
+	want      bool
+	wantDepth int
+}{
+	{rels: "", t: "GO:0000014", q: "GO:0000014", want: true, wantDepth: 0},
+	{rels: "", t: "GO:0008150", q: "GO:0000011", want: true, wantDepth: 2},
+	{rels: "", t: "GO:0008150", q: "GO:0000014", want: true, wantDepth: 2},
+	{rels: "", t: "GO:0000010", q: "GO:0000014", want: false, wantDepth: -1},
+	{rels: "", t: "GO:0000011", q: "GO:0000012", want: false, wantDepth: -1},
+	{rels: "", t: "GO:0000014", q: "GO:0008150", want: false, wantDepth: -1},
+	{rels: "part_of", t: "GO:0000010", q: "GO:0000014", want: true, wantDepth: 1},
+	{rels: "part_of", t: "GO:0000010", q: "GO:0000012", want: true, wantDepth: 2},
+	{rels: "part_of", t: "GO:0000011", q: "GO:0000014", want: false, wantDepth: -1},
+	{rels: "part_of", t: "GO:0000012", q: "GO:0000013", want: false, wantDepth: -1},
+	{rels: "part_of,regulates", t: "GO:0000012", q: "GO:0000013", want: true, wantDepth: 1},
+	{rels: "part_of,regulates", t: "GO:0000010", q: "GO:0000013", want: true, wantDepth: 3},
+	{rels: "part_of,regulates", t: "GO:0000011", q: "GO:0000014", want: true, wantDepth: 3},
+	{rels: "part_of,regulates", t: "GO:0000010", q: "GO:0000014", want: true, wantDepth: 1},
+	{rels: "part_of,regulates", t: "GO:0000014", q: "GO:0000010", want: false, wantDepth: -1},
+}
+
+func TestAncestry(t *testing.T) {
+	for _, test := range ancestryTests {
+		g, terms := testAncestryGraph(t, test.rels)
+		rels, err := ParseRelations(test.rels)
+		if err != nil {
+			t.Fatalf("unexpected error parsing relations: %v", err)
+		}
+		anc := newAncestry(g, rels)
+
+		tt, q := terms[test.t], terms[test.q]
+		got, depth := anc.isDescendantOf(tt, q)
+		if got != test.want || depth != test.wantDepth {
+			t.Errorf("unexpected result for %s below %s with relations %q: got:%t,%d want:%t,%d",
+				test.q, test.t, test.rels, got, depth, test.want, test.wantDepth)
+		}
+
+		// Check all pairs against a fresh walk of the DAG
+		// from the query.
+		for _, tt := range terms {
+			for _, q := range terms {
+				got, depth := anc.isDescendantOf(tt, q)
+				want := walkDepth(g, rels, tt, q)
+				if got != (want >= 0) || depth != want {
+					t.Errorf("unexpected result for %s below %s with relations %q: got:%t,%d want depth:%d",
+						q.Value, tt.Value, test.rels, got, depth, want)
+				}
+			}
+		}
+	}
+}
+
+func TestAncestryNonGO(t *testing.T) {
+	g, terms := testAncestryGraph(t, "")
+	anc := newAncestry(g, nil)
+	gene := rdf.Term{Value: "<ensembl:ENSG00000000001>", UID: -1}
+	got, depth := anc.isDescendantOf(terms["GO:0008150"], gene)
+	if got || depth != -1 {
+		t.Errorf("unexpected result for non-GO query: got:%t,%d want:false,-1", got, depth)
+	}
+}
+
+func TestAncestryConcurrent(t *testing.T) {
+	const workers = 8
+
+	g, terms := testAncestryGraph(t, "part_of,regulates")
+	rels, err := ParseRelations("part_of,regulates")
+	if err != nil {
+		t.Fatalf("unexpected error parsing relations: %v", err)
+	}
+	want := make(map[[2]int64]int)
+	for _, tt := range terms {
+		for _, q := range terms {
+			want[[2]int64{tt.ID(), q.ID()}] = walkDepth(g, rels, tt, q)
+		}
+	}
+
+	anc := newAncestry(g, rels)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, tt := range terms {
+				for _, q := range terms {
+					_, depth := anc.isDescendantOf(tt, q)
+					if w := want[[2]int64{tt.ID(), q.ID()}]; depth != w {
+						t.Errorf("unexpected depth for %s below %s: got:%d want:%d", q.Value, tt.Value, depth, w)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// testAncestryGraph returns the graph of the testAncestryOBO ontology
+// loaded with the given relations and its GO terms keyed by GO ID.
+func testAncestryGraph(t *testing.T, relations string) (*gogo.Graph, map[string]rdf.Term) {
+	rels, err := ParseRelations(relations)
+	if err != nil {
+		t.Fatalf("unexpected error parsing relations: %v", err)
+	}
+	o, err := LoadOntology(context.Background(), strings.NewReader(testAncestryOBO), true, rels)
+	if err != nil {
+		t.Fatalf("unexpected error loading ontology: %v", err)
+	}
+	g := o.Graph()
+	terms := make(map[string]rdf.Term)
+	for _, id := range []string{"GO:0008150", "GO:0000010", "GO:0000011", "GO:0000012", "GO:0000013", "GO:0000014"} {
+		term, ok := g.TermFor("<obo:GO_" + strings.TrimPrefix(id, "GO:") + ">")
+		if !ok {
+			t.Fatalf("missing term for %s", id)
+		}
+		terms[id] = term
+	}
+	return g, terms
+}
+
+// walkDepth returns the number of levels separating q from its ancestor t
+// found by a breadth first walk of g from q following rels, or -1 if t is
+// not an ancestor of q.
+func walkDepth(g *gogo.Graph, rels Relations, t, q rdf.Term) int {
+	depth := -1
+	bf := traverse.BreadthFirst{Traverse: rels.isSubClassOfGO}
+	bf.Walk(g, q, func(n graph.Node, d int) bool {
+		if n.ID() == t.ID() {
+			depth = d
+			return true
+		}
+		return false
+	})
+	return depth
+}
@@ -0,0 +1,53 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"math"
+	"math/rand"
+)
+
+// bootstrapSeed is the seed for bootstrap resampling
+// so that results are reproducible.
+const bootstrapSeed = 1
+
+// bootstrapRanks returns the optimal ranks of n bootstrap replicates of m
+// formed by resampling the rows of m with replacement. The ranks are
+// calculated as for Rank.
+//
+// A replicate that includes row i k times has the same singular values
+// as m with row i scaled by √k, so replicates are formed by scaling rows
+// rather than by constructing the resampled matrix.
+func bootstrapRanks(m *Matrix, n int, cut float64, thresh Threshold, limit, rank int) ([]float64, error) {
+	rnd := rand.New(rand.NewSource(bootstrapSeed))
+	rows, cols := m.Dims()
+	scale := make([]float64, rows)
+	ranks := make([]float64, n)
+	for i := range ranks {
+		for j := range scale {
+			scale[j] = 0
+		}
+		for j := 0; j < rows; j++ {
+			scale[rnd.Intn(rows)]++
+		}
+		for j, k := range scale {
+			scale[j] = math.Sqrt(k)
+		}
+
+		r := m.scaleRows(scale)
+		sigma, partial, err := singularValues(r, limit, rank)
+		if err != nil {
+			return nil, err
+		}
+		var resid *residual
+		if partial {
+			resid = residualOf(r, sigma)
+		}
+		sigmaCut := CutValues(sigma, cut)
+		t, _ := thresh.tau(rows, cols, sigmaCut, resid)
+		ranks[i] = float64(idxBelow(t, sigmaCut))
+	}
+	return ranks, nil
+}
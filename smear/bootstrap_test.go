@@ -0,0 +1,101 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBootstrapRanks(t *testing.T) {
+	const (
+		replicates = 50
+		wantRank   = 3
+	)
+
+	rnd := rand.New(rand.NewSource(1))
+	m := lowRankMatrix(rnd, 400, 40, []float64{300, 200, 100}, 0.5)
+	thresh, err := NewThreshold(UnknownNoise, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating threshold: %v", err)
+	}
+
+	ranks, err := bootstrapRanks(m, replicates, 0, thresh, math.MaxInt32, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ranks) != replicates {
+		t.Fatalf("unexpected number of bootstrap ranks: got:%d want:%d", len(ranks), replicates)
+	}
+	var n int
+	for _, r := range ranks {
+		if r == wantRank {
+			n++
+		}
+	}
+	if n < replicates*9/10 {
+		t.Errorf("bootstrap ranks not concentrated at %d: %v", wantRank, ranks)
+	}
+
+	again, err := bootstrapRanks(m, replicates, 0, thresh, math.MaxInt32, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(again, ranks) {
+		t.Errorf("bootstrap ranks not reproducible:\ngot: %v\nwant:%v", again, ranks)
+	}
+}
+
+func TestRankBootstrap(t *testing.T) {
+	const replicates = 50
+
+	rnd := rand.New(rand.NewSource(1))
+	m := lowRankMatrix(rnd, 400, 40, []float64{300, 200, 100}, 0.5)
+	thresh, err := NewThreshold(UnknownNoise, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating threshold: %v", err)
+	}
+	opts := RankOptions{Frac: 0.75, Threshold: thresh, SVDLimit: math.MaxInt32, Bootstrap: replicates}
+
+	s, err := Rank(m, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Bootstrap != replicates {
+		t.Errorf("unexpected number of bootstrap replicates: got:%d want:%d", s.Bootstrap, replicates)
+	}
+	if math.Abs(s.RankMean-float64(s.OptimalRank)) > 0.5 {
+		t.Errorf("unexpected bootstrap rank mean: got:%v want close to:%d", s.RankMean, s.OptimalRank)
+	}
+	if s.RankStdDev < 0 || math.IsNaN(s.RankStdDev) {
+		t.Errorf("unexpected bootstrap rank standard deviation: %v", s.RankStdDev)
+	}
+	if s.RankCI[0] > s.RankMean || s.RankMean > s.RankCI[1] {
+		t.Errorf("bootstrap rank mean outside interval: mean:%v interval:%v", s.RankMean, s.RankCI)
+	}
+	if s.RankCI[0] > float64(s.OptimalRank) || float64(s.OptimalRank) > s.RankCI[1] {
+		t.Errorf("optimal rank outside bootstrap interval: rank:%d interval:%v", s.OptimalRank, s.RankCI)
+	}
+
+	again, err := Rank(m, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again.RankMean != s.RankMean || again.RankStdDev != s.RankStdDev || again.RankCI != s.RankCI {
+		t.Errorf("bootstrap intervals not reproducible: got:%v±%v %v want:%v±%v %v",
+			again.RankMean, again.RankStdDev, again.RankCI, s.RankMean, s.RankStdDev, s.RankCI)
+	}
+
+	opts.Bootstrap = 0
+	s, err = Rank(m, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Bootstrap != 0 || s.RankMean != 0 || s.RankStdDev != 0 || s.RankCI != [2]float64{} {
+		t.Errorf("unexpected bootstrap fields without bootstrap: %d %v±%v %v", s.Bootstrap, s.RankMean, s.RankStdDev, s.RankCI)
+	}
+}
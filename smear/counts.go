@@ -0,0 +1,285 @@
+// Copyright ©2020 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CountData holds count data for a set of named samples each with a
+// collection of features. CountData values should be created with
+// NewCountData or ReadCounts.
+type CountData struct {
+	// Names is the names of the samples.
+	Names []string
+
+	// Counts holds a set of counts for
+	// features keyed by the map key.
+	// The length of each []float64
+	// must match the length of Names
+	// and indexing into the []float64
+	// reflects indexing into Names.
+	Counts map[string][]float64
+
+	// GeneIDs is the set of feature
+	// identifiers in the order of their
+	// internal gene index.
+	GeneIDs []string
+
+	// geneIdx is the mapping from external
+	// gene identifier to internal gene index.
+	geneIdx map[string]int
+}
+
+// NewCountData returns a CountData for the given sample names, ordered
+// gene identifiers and counts. Each gene identifier must have counts for
+// each of the samples.
+func NewCountData(names, geneIDs []string, counts map[string][]float64) (*CountData, error) {
+	geneIdx := make(map[string]int, len(geneIDs))
+	for i, id := range geneIDs {
+		if _, ok := geneIdx[id]; ok {
+			return nil, fmt.Errorf("duplicate gene identifier: %q", id)
+		}
+		c, ok := counts[id]
+		if !ok {
+			return nil, fmt.Errorf("no counts for gene identifier: %q", id)
+		}
+		if len(c) != len(names) {
+			return nil, fmt.Errorf("mismatched number of counts for %q: %d != %d", id, len(c), len(names))
+		}
+		geneIdx[id] = i
+	}
+	if len(counts) != len(geneIDs) {
+		return nil, fmt.Errorf("mismatched number of genes and counts: %d != %d", len(geneIDs), len(counts))
+	}
+	return &CountData{Names: names, Counts: counts, GeneIDs: geneIDs, geneIdx: geneIdx}, nil
+}
+
+// Index returns the internal gene index of the gene identifier id.
+func (d *CountData) Index(id string) (idx int, ok bool) {
+	idx, ok = d.geneIdx[id]
+	return idx, ok
+}
+
+// Count file formats.
+const (
+	FormatAuto          = "auto"
+	FormatTSV           = "tsv"
+	FormatFeatureCounts = "featurecounts"
+	FormatSalmon        = "salmon"
+	FormatKallisto      = "kallisto"
+)
+
+// Quantification measures.
+const (
+	QuantCounts = "counts"
+	QuantTPM    = "tpm"
+)
+
+// quantHeaders holds the header columns of per-sample quantification
+// formats and the column holding each quantification measure.
+var quantHeaders = map[string]struct {
+	header  []string
+	columns map[string]string
+}{
+	FormatSalmon: {
+		header:  []string{"Name", "Length", "EffectiveLength", "TPM", "NumReads"},
+		columns: map[string]string{QuantCounts: "NumReads", QuantTPM: "TPM"},
+	},
+	FormatKallisto: {
+		header:  []string{"target_id", "length", "eff_length", "est_counts", "tpm"},
+		columns: map[string]string{QuantCounts: "est_counts", QuantTPM: "tpm"},
+	},
+}
+
+// featureCountsAnnotation is the set of annotation columns between the
+// Geneid column and the sample columns of featureCounts output.
+var featureCountsAnnotation = []string{"Chr", "Start", "End", "Strand", "Length"}
+
+// ReadCounts returns the count data held in r in the given format using
+// the quant quantification measure. The name is used as the sample name
+// for single sample formats. If format is FormatAuto, the format is
+// determined from the header. Reading stops if ctx is cancelled and the
+// context's error is returned.
+func ReadCounts(ctx context.Context, r io.Reader, name, format, quant string) (*CountData, error) {
+	switch format {
+	case FormatAuto, FormatTSV, FormatFeatureCounts, FormatSalmon, FormatKallisto:
+	default:
+		return nil, fmt.Errorf("unknown counts format: %q", format)
+	}
+	switch quant {
+	case QuantCounts, QuantTPM:
+	default:
+		return nil, fmt.Errorf("unknown quantification measure: %q", quant)
+	}
+
+	c := csv.NewReader(r)
+	c.Comma = '\t'
+	c.Comment = '#'
+
+	labels, err := c.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if format == FormatAuto {
+		format, err = detectFormat(labels)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Determine the sample names and the columns holding
+	// their values.
+	var (
+		samples []string
+		columns []int
+	)
+	switch format {
+	case FormatTSV, FormatFeatureCounts:
+		if labels[0] != "Geneid" {
+			return nil, fmt.Errorf(`unexpected first column name: %q != "Geneid"`, labels[0])
+		}
+		if quant != QuantCounts {
+			return nil, fmt.Errorf("%s format only provides %s", format, QuantCounts)
+		}
+		first := 1
+		if format == FormatFeatureCounts {
+			if !hasPrefix(labels[1:], featureCountsAnnotation) {
+				return nil, fmt.Errorf("missing featureCounts annotation columns: %q", labels)
+			}
+			first += len(featureCountsAnnotation)
+		}
+		for i, l := range labels[first:] {
+			if format == FormatFeatureCounts {
+				// featureCounts names samples by their alignment path.
+				l = strings.TrimSuffix(filepath.Base(l), ".bam")
+			}
+			samples = append(samples, l)
+			columns = append(columns, first+i)
+		}
+	case FormatSalmon, FormatKallisto:
+		q := quantHeaders[format]
+		if !hasPrefix(labels, q.header[:1]) {
+			return nil, fmt.Errorf("unexpected first column name: %q != %q", labels[0], q.header[0])
+		}
+		col := -1
+		for i, l := range labels {
+			if l == q.columns[quant] {
+				col = i
+				break
+			}
+		}
+		if col < 0 {
+			return nil, fmt.Errorf("missing %s column", q.columns[quant])
+		}
+		samples = []string{name}
+		columns = []int{col}
+	}
+
+	data := make(map[string][]float64)
+	geneIdx := make(map[string]int)
+	var geneIDs []string
+
+	c.ReuseRecord = true
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+		counts, err := c.Read()
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		geneid := counts[0]
+		geneIdx[geneid] = len(geneIDs)
+		geneIDs = append(geneIDs, geneid)
+		for i, col := range columns {
+			v, err := strconv.ParseFloat(counts[col], 64)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing value for %q in sample %q: %v", geneid, samples[i], err)
+			}
+			data[geneid] = append(data[geneid], v)
+		}
+	}
+
+	return &CountData{
+		Names:   samples,
+		Counts:  data,
+		GeneIDs: geneIDs,
+		geneIdx: geneIdx,
+	}, nil
+}
+
+// detectFormat returns the counts file format indicated by the header
+// labels.
+func detectFormat(labels []string) (string, error) {
+	switch {
+	case labels[0] == "Geneid" && hasPrefix(labels[1:], featureCountsAnnotation):
+		return FormatFeatureCounts, nil
+	case labels[0] == "Geneid":
+		return FormatTSV, nil
+	}
+	for _, format := range []string{FormatSalmon, FormatKallisto} {
+		if hasPrefix(labels, quantHeaders[format].header) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown counts format header: %q", labels)
+}
+
+// hasPrefix returns whether s starts with the elements of prefix.
+func hasPrefix(s, prefix []string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i, p := range prefix {
+		if s[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeCounts returns the count data for the union of samples. Features
+// missing from a sample are given a zero count. Samples retain their order
+// and features are ordered by first appearance.
+func MergeCounts(samples []*CountData) *CountData {
+	var names []string
+	for _, s := range samples {
+		names = append(names, s.Names...)
+	}
+	merged := &CountData{
+		Names:   names,
+		Counts:  make(map[string][]float64),
+		geneIdx: make(map[string]int),
+	}
+	offset := 0
+	for _, s := range samples {
+		for _, id := range s.GeneIDs {
+			counts, ok := merged.Counts[id]
+			if !ok {
+				counts = make([]float64, len(names))
+				merged.Counts[id] = counts
+				merged.geneIdx[id] = len(merged.GeneIDs)
+				merged.GeneIDs = append(merged.GeneIDs, id)
+			}
+			copy(counts[offset:offset+len(s.Names)], s.Counts[id])
+		}
+		offset += len(s.Names)
+	}
+	return merged
+}
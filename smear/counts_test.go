@@ -0,0 +1,311 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testSalmon = `Name	Length	EffectiveLength	TPM	NumReads
+ENST00000000001.1	1000	800	12.5	10
+ENST00000000002.1	2000	1800	2.5	4.5
+`
+
+	testKallisto = `target_id	length	eff_length	est_counts	tpm
+ENST00000000001.1	1000	800	10	12.5
+ENST00000000002.1	2000	1800	4.5	2.5
+`
+
+	testFeatureCounts = `# Program:featureCounts v2.0.1; Command:"featureCounts" "-a" "genes.gtf" "-o" "counts.txt" "a/S1.bam" "b/S2.bam"
+Geneid	Chr	Start	End	Strand	Length	a/S1.bam	b/S2.bam
+ENSG00000000001	1	100	200	+	101	3	0
+ENSG00000000002	1;1	300;400	350;500	-;-	152	5	7
+`
+)
+
+var detectFormatTests = []struct {
+	labels  []string
+	want    string
+	wantErr bool
+}{
+	{labels: []string{"Geneid", "S1", "S2"}, want: FormatTSV},
+	{labels: []string{"Geneid"}, want: FormatTSV},
+	{labels: []string{"Geneid", "Chr", "Start", "End", "Strand", "Length", "S1.bam"}, want: FormatFeatureCounts},
+	{labels: []string{"Geneid", "Chr", "Start", "End", "Strand", "S1.bam"}, want: FormatTSV},
+	{labels: []string{"Name", "Length", "EffectiveLength", "TPM", "NumReads"}, want: FormatSalmon},
+	{labels: []string{"target_id", "length", "eff_length", "est_counts", "tpm"}, want: FormatKallisto},
+	{labels: []string{"Name", "Length", "EffectiveLength", "TPM"}, wantErr: true},
+	{labels: []string{"gene_id", "S1"}, wantErr: true},
+}
+
+func TestDetectFormat(t *testing.T) {
+	for _, test := range detectFormatTests {
+		got, err := detectFormat(test.labels)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %q: got:%v want error:%t", test.labels, err, test.wantErr)
+		}
+		if got != test.want {
+			t.Errorf("unexpected format for %q: got:%q want:%q", test.labels, got, test.want)
+		}
+	}
+}
+
+var readCountsTests = []struct {
+	name    string
+	doc     string
+	format  string
+	quant   string
+	want    *CountData
+	wantErr bool
+}{
+	{
+		name:   "tsv",
+		doc:    testCounts,
+		format: FormatAuto,
+		quant:  QuantCounts,
+		want: &CountData{
+			Names: []string{"S1", "S2"},
+			Counts: map[string][]float64{
+				"ENSG00000000001": {3, 0},
+				"ENSG00000000002": {5, 7},
+				"ENSG00000000003": {1, 1},
+			},
+			GeneIDs: []string{"ENSG00000000001", "ENSG00000000002", "ENSG00000000003"},
+		},
+	},
+	{
+		name:    "tsv tpm",
+		doc:     testCounts,
+		format:  FormatTSV,
+		quant:   QuantTPM,
+		wantErr: true,
+	},
+	{
+		name:   "featurecounts",
+		doc:    testFeatureCounts,
+		format: FormatAuto,
+		quant:  QuantCounts,
+		want: &CountData{
+			Names: []string{"S1", "S2"},
+			Counts: map[string][]float64{
+				"ENSG00000000001": {3, 0},
+				"ENSG00000000002": {5, 7},
+			},
+			GeneIDs: []string{"ENSG00000000001", "ENSG00000000002"},
+		},
+	},
+	{
+		name:    "featurecounts explicit tsv",
+		doc:     testCounts,
+		format:  FormatFeatureCounts,
+		quant:   QuantCounts,
+		wantErr: true,
+	},
+	{
+		name:   "salmon counts",
+		doc:    testSalmon,
+		format: FormatAuto,
+		quant:  QuantCounts,
+		want: &CountData{
+			Names: []string{"sample"},
+			Counts: map[string][]float64{
+				"ENST00000000001.1": {10},
+				"ENST00000000002.1": {4.5},
+			},
+			GeneIDs: []string{"ENST00000000001.1", "ENST00000000002.1"},
+		},
+	},
+	{
+		name:   "salmon tpm",
+		doc:    testSalmon,
+		format: FormatSalmon,
+		quant:  QuantTPM,
+		want: &CountData{
+			Names: []string{"sample"},
+			Counts: map[string][]float64{
+				"ENST00000000001.1": {12.5},
+				"ENST00000000002.1": {2.5},
+			},
+			GeneIDs: []string{"ENST00000000001.1", "ENST00000000002.1"},
+		},
+	},
+	{
+		name:   "kallisto counts",
+		doc:    testKallisto,
+		format: FormatAuto,
+		quant:  QuantCounts,
+		want: &CountData{
+			Names: []string{"sample"},
+			Counts: map[string][]float64{
+				"ENST00000000001.1": {10},
+				"ENST00000000002.1": {4.5},
+			},
+			GeneIDs: []string{"ENST00000000001.1", "ENST00000000002.1"},
+		},
+	},
+	{
+		name:   "kallisto tpm",
+		doc:    testKallisto,
+		format: FormatKallisto,
+		quant:  QuantTPM,
+		want: &CountData{
+			Names: []string{"sample"},
+			Counts: map[string][]float64{
+				"ENST00000000001.1": {12.5},
+				"ENST00000000002.1": {2.5},
+			},
+			GeneIDs: []string{"ENST00000000001.1", "ENST00000000002.1"},
+		},
+	},
+	{
+		name:    "kallisto as salmon",
+		doc:     testKallisto,
+		format:  FormatSalmon,
+		quant:   QuantCounts,
+		wantErr: true,
+	},
+	{
+		name:    "unknown format",
+		doc:     testCounts,
+		format:  "htseq",
+		quant:   QuantCounts,
+		wantErr: true,
+	},
+	{
+		name:    "unknown quant",
+		doc:     testSalmon,
+		format:  FormatAuto,
+		quant:   "fpkm",
+		wantErr: true,
+	},
+	{
+		name:    "empty",
+		doc:     "",
+		format:  FormatAuto,
+		quant:   QuantCounts,
+		wantErr: true,
+	},
+}
+
+func TestReadCounts(t *testing.T) {
+	for _, test := range readCountsTests {
+		got, err := ReadCounts(context.Background(), strings.NewReader(test.doc), "sample", test.format, test.quant)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %q: got:%v want error:%t", test.name, err, test.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		checkCountData(t, test.name, got, test.want)
+	}
+}
+
+var mergeCountsTests = []struct {
+	name    string
+	samples []*CountData
+	want    *CountData
+}{
+	{
+		name: "disjoint",
+		samples: []*CountData{
+			{
+				Names:   []string{"S1"},
+				Counts:  map[string][]float64{"ENSG00000000001": {1}, "ENSG00000000002": {2}},
+				GeneIDs: []string{"ENSG00000000001", "ENSG00000000002"},
+			},
+			{
+				Names:   []string{"S2"},
+				Counts:  map[string][]float64{"ENSG00000000003": {3}},
+				GeneIDs: []string{"ENSG00000000003"},
+			},
+		},
+		want: &CountData{
+			Names: []string{"S1", "S2"},
+			Counts: map[string][]float64{
+				"ENSG00000000001": {1, 0},
+				"ENSG00000000002": {2, 0},
+				"ENSG00000000003": {0, 3},
+			},
+			GeneIDs: []string{"ENSG00000000001", "ENSG00000000002", "ENSG00000000003"},
+		},
+	},
+	{
+		name: "overlapping",
+		samples: []*CountData{
+			{
+				Names:   []string{"S1"},
+				Counts:  map[string][]float64{"ENSG00000000002": {2}, "ENSG00000000001": {1}},
+				GeneIDs: []string{"ENSG00000000002", "ENSG00000000001"},
+			},
+			{
+				Names:   []string{"S2", "S3"},
+				Counts:  map[string][]float64{"ENSG00000000001": {4, 5}, "ENSG00000000003": {6, 7}},
+				GeneIDs: []string{"ENSG00000000001", "ENSG00000000003"},
+			},
+			{
+				Names:   []string{"S4"},
+				Counts:  map[string][]float64{"ENSG00000000003": {8}},
+				GeneIDs: []string{"ENSG00000000003"},
+			},
+		},
+		want: &CountData{
+			Names: []string{"S1", "S2", "S3", "S4"},
+			Counts: map[string][]float64{
+				"ENSG00000000001": {1, 4, 5, 0},
+				"ENSG00000000002": {2, 0, 0, 0},
+				"ENSG00000000003": {0, 6, 7, 8},
+			},
+			GeneIDs: []string{"ENSG00000000002", "ENSG00000000001", "ENSG00000000003"},
+		},
+	},
+	{
+		name: "single",
+		samples: []*CountData{
+			{
+				Names:   []string{"S1", "S2"},
+				Counts:  map[string][]float64{"ENSG00000000001": {1, 2}},
+				GeneIDs: []string{"ENSG00000000001"},
+			},
+		},
+		want: &CountData{
+			Names:   []string{"S1", "S2"},
+			Counts:  map[string][]float64{"ENSG00000000001": {1, 2}},
+			GeneIDs: []string{"ENSG00000000001"},
+		},
+	},
+}
+
+func TestMergeCounts(t *testing.T) {
+	for _, test := range mergeCountsTests {
+		got := MergeCounts(test.samples)
+		checkCountData(t, test.name, got, test.want)
+	}
+}
+
+// checkCountData checks that got holds the same samples and features as
+// want and that the internal gene index of got is consistent with its
+// GeneIDs.
+func checkCountData(t *testing.T, name string, got, want *CountData) {
+	t.Helper()
+	if !reflect.DeepEqual(got.Names, want.Names) {
+		t.Errorf("unexpected sample names for %q: got:%q want:%q", name, got.Names, want.Names)
+	}
+	if !reflect.DeepEqual(got.GeneIDs, want.GeneIDs) {
+		t.Errorf("unexpected gene IDs for %q: got:%q want:%q", name, got.GeneIDs, want.GeneIDs)
+	}
+	if !reflect.DeepEqual(got.Counts, want.Counts) {
+		t.Errorf("unexpected counts for %q: got:%v want:%v", name, got.Counts, want.Counts)
+	}
+	for i, id := range got.GeneIDs {
+		idx, ok := got.Index(id)
+		if !ok || idx != i {
+			t.Errorf("unexpected index for %s in %q: got:%d,%t want:%d,true", id, name, idx, ok, i)
+		}
+	}
+}
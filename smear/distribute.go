@@ -0,0 +1,208 @@
+// Copyright ©2020 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+
+	"github.com/kortschak/smeargol/internal/bitset"
+)
+
+type ontoCounts struct {
+	vector []bitset.Set
+}
+
+// Painting holds count data painted onto the terms of an ontology.
+type Painting struct {
+	data  *CountData
+	roots []rdf.Term
+
+	// ontoData holds the painted genes
+	// for each term below each root.
+	ontoData []map[string]ontoCounts
+
+	// unannotated holds the genes that
+	// have no GO term annotation.
+	unannotated []string
+}
+
+// Distribute paints the ancestor closure of each of the leaf-most terms
+// associated with each of the genes held by data, for each of the samples
+// in data independently.
+// Each gene ontology aspect is analysed separately since the aspects are not
+// connected; the aspects are indexed by the order of the ontology's Roots.
+// Genes are distributed over the given number of workers, each painting its
+// own partial ontology data, and the partial results are merged once all
+// genes have been painted. If workers is less than one, a single worker is
+// used.
+// Counts are distributed along the Relations used to load o. If ctx is
+// cancelled, painting stops and the context's error is returned.
+func Distribute(ctx context.Context, o *Ontology, data *CountData, workers int) (*Painting, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	roots := o.Roots()
+	genes := make(chan string)
+	partial := make([][]map[string]ontoCounts, workers)
+	missing := make([][]string, workers)
+	var wg sync.WaitGroup
+	for w := range partial {
+		w := w
+		partial[w] = newOntoData(len(roots))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for geneid := range genes {
+				counts := data.Counts[geneid]
+				leafiest, ok := leafiestFor(geneid, o.graph, roots, o.anc)
+				if !ok {
+					missing[w] = append(missing[w], geneid)
+					continue
+				}
+				for i, aspect := range leafiest {
+					for _, l := range aspect {
+						for _, a := range o.anc.of(l) {
+							updateOntoData(partial[w][i], a.term, geneid, counts, data)
+						}
+					}
+				}
+			}
+		}()
+	}
+feed:
+	for geneid := range data.Counts {
+		select {
+		case genes <- geneid:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(genes)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ontoData := partial[0]
+	for _, p := range partial[1:] {
+		for i, aspect := range p {
+			mergeOntoData(ontoData[i], aspect)
+		}
+	}
+	var unannotated []string
+	for _, m := range missing {
+		unannotated = append(unannotated, m...)
+	}
+	sort.Strings(unannotated)
+	return &Painting{data: data, roots: roots, ontoData: ontoData, unannotated: unannotated}, nil
+}
+
+// Roots returns the roots of the painted ontology. The index of a root
+// in the returned slice is used to identify its aspect.
+func (p *Painting) Roots() []rdf.Term {
+	return p.roots
+}
+
+// Unannotated returns the identifiers of genes in the count data that have
+// no GO term annotation, sorted lexically.
+func (p *Painting) Unannotated() []string {
+	return p.unannotated
+}
+
+// Genes returns the set of genes painted onto term in the given sample for
+// the aspect with index root. The returned set is a view of the painting
+// and is not copied. The indices of the set correspond to the GeneIDs of
+// the painted count data. If no gene has been painted onto term in any
+// sample, ok is returned false.
+func (p *Painting) Genes(root int, term string, sample int) (genes GeneSet, ok bool) {
+	counts, ok := p.ontoData[root][term]
+	if !ok {
+		return GeneSet{}, false
+	}
+	return GeneSet{set: &counts.vector[sample]}, true
+}
+
+// GeneMatrix returns the gene × term matrix of counts for the given sample
+// and terms in the aspect with index root. Each column holds the counts of
+// the genes painted onto the corresponding term, and rows correspond to the
+// GeneIDs of the painted count data.
+func (p *Painting) GeneMatrix(root, sample int, terms []string) *Matrix {
+	data := p.data
+	m := newMatrix(len(data.GeneIDs), len(terms))
+	for _, term := range terms {
+		counts, ok := p.ontoData[root][term]
+		if ok {
+			counts.vector[sample].Do(func(row int) {
+				m.append(row, data.Counts[data.GeneIDs[row]][sample])
+			})
+		}
+		m.endColumn()
+	}
+	return m
+}
+
+// TermMatrix returns the term × sample matrix of the counts summed over
+// the genes painted onto each of the terms in the aspect with index root.
+// Columns correspond to the Names of the painted count data.
+func (p *Painting) TermMatrix(root int, terms []string) *Matrix {
+	data := p.data
+	m := newMatrix(len(terms), len(data.Names))
+	for sample := range data.Names {
+		for row, term := range terms {
+			counts, ok := p.ontoData[root][term]
+			if !ok {
+				continue
+			}
+			var sum float64
+			counts.vector[sample].Do(func(gene int) {
+				sum += data.Counts[data.GeneIDs[gene]][sample]
+			})
+			m.append(row, sum)
+		}
+		m.endColumn()
+	}
+	return m
+}
+
+// newOntoData returns empty ontology data for n roots.
+func newOntoData(n int) []map[string]ontoCounts {
+	ontoData := make([]map[string]ontoCounts, n)
+	for i := range ontoData {
+		ontoData[i] = make(map[string]ontoCounts)
+	}
+	return ontoData
+}
+
+// mergeOntoData merges the painted genes in src into dst.
+func mergeOntoData(dst, src map[string]ontoCounts) {
+	for term, counts := range src {
+		d, ok := dst[term]
+		if !ok {
+			dst[term] = counts
+			continue
+		}
+		for j := range d.vector {
+			d.vector[j].Or(&d.vector[j], &counts.vector[j])
+		}
+	}
+}
+
+func updateOntoData(ontoData map[string]ontoCounts, t rdf.Term, geneid string, counts []float64, data *CountData) {
+	dst, ok := ontoData[t.Value]
+	if !ok {
+		dst.vector = make([]bitset.Set, len(counts))
+		ontoData[t.Value] = dst
+	}
+	for j, c := range counts {
+		if c == 0 {
+			continue
+		}
+		dst.vector[j].Set(data.geneIdx[geneid])
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"testing"
+
+	"github.com/kortschak/smeargol/internal/bitset"
+)
+
+func TestTermMatrix(t *testing.T) {
+	data := &CountData{
+		Names:   []string{"S1", "S2"},
+		GeneIDs: []string{"G0", "G1", "G2"},
+		Counts: map[string][]float64{
+			"G0": {1, 10},
+			"G1": {2, 0},
+			"G2": {4, 40},
+		},
+	}
+	p := &Painting{
+		data: data,
+		ontoData: []map[string]ontoCounts{{
+			"T1": {vector: []bitset.Set{testSet(0, 1), testSet(0)}},
+			"T2": {vector: []bitset.Set{testSet(1, 2), testSet(2)}},
+			"T3": {vector: []bitset.Set{testSet(), testSet()}},
+		}},
+	}
+
+	terms := []string{"T2", "T1", "T3", "T4"}
+	want := [][]float64{
+		{6, 40},
+		{3, 10},
+		{0, 0},
+		{0, 0},
+	}
+	m := p.TermMatrix(0, terms)
+	r, c := m.Dims()
+	if r != len(terms) || c != len(data.Names) {
+		t.Fatalf("unexpected dimensions: got:%d×%d want:%d×%d", r, c, len(terms), len(data.Names))
+	}
+	for i, row := range want {
+		for j, v := range row {
+			if got := m.At(i, j); got != v {
+				t.Errorf("unexpected sum for %s in %s: got:%v want:%v", terms[i], data.Names[j], got, v)
+			}
+		}
+	}
+}
+
+// testSet returns a bitset.Set holding the given members.
+func testSet(members ...int) bitset.Set {
+	var s bitset.Set
+	for _, i := range members {
+		s.Set(i)
+	}
+	return s
+}
@@ -0,0 +1,14 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package smear implements distribution of gene count data across the Gene
+// Ontology DAG and the rank analysis of the resulting GO level matrices.
+//
+// A typical analysis reads count data with ReadCounts, loads the ontology
+// with LoadOntology, adds gene annotations to it with Annotate, paints the
+// counts onto the ontology with Distribute and then, for each root, obtains
+// the ontology's Levels and calculates the Rank of each level's matrices.
+// The graph analysis assumes Ensembl gene identifiers and Gene Ontology
+// graph structure.
+package smear // import "github.com/kortschak/smeargol/smear"
@@ -0,0 +1,94 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"strings"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+)
+
+// evidenceGroups holds named sets of GO evidence codes that may be used in
+// place of the codes themselves when specifying an evidence filter.
+// See http://geneontology.org/docs/guide-go-evidence-codes/.
+var evidenceGroups = map[string][]string{
+	"experimental":      {"EXP", "IDA", "IPI", "IMP", "IGI", "IEP"},
+	"high-throughput":   {"HTP", "HDA", "HMP", "HGI", "HEP"},
+	"phylogenetic":      {"IBA", "IBD", "IKR", "IRD"},
+	"computational":     {"ISS", "ISO", "ISA", "ISM", "IGC", "RCA"},
+	"author-statement":  {"TAS", "NAS"},
+	"curator-statement": {"IC", "ND"},
+	"electronic":        {"IEA"},
+}
+
+// EvidenceFilter filters annotations by their evidence code. The zero
+// value accepts all annotations.
+type EvidenceFilter struct {
+	include map[string]bool
+	exclude map[string]bool
+}
+
+// NewEvidenceFilter returns an EvidenceFilter that accepts annotations with
+// evidence codes in the comma-separated include list and not in the comma-
+// separated exclude list. An empty include list accepts all evidence codes.
+// Elements of the lists may be evidence codes or the names of groups in
+// the evidence groups experimental, high-throughput, phylogenetic,
+// computational, author-statement, curator-statement and electronic.
+func NewEvidenceFilter(include, exclude string) EvidenceFilter {
+	return EvidenceFilter{
+		include: evidenceSet(include),
+		exclude: evidenceSet(exclude),
+	}
+}
+
+func evidenceSet(list string) map[string]bool {
+	if list == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, code := range strings.Split(list, ",") {
+		code = strings.TrimSpace(code)
+		if group, ok := evidenceGroups[strings.ToLower(code)]; ok {
+			for _, c := range group {
+				set[c] = true
+			}
+			continue
+		}
+		set[strings.ToUpper(code)] = true
+	}
+	return set
+}
+
+// accept returns whether an annotation with the given evidence code should
+// be retained. An empty code indicates that the evidence for the annotation
+// is not known; these annotations are only accepted when no include list
+// has been provided.
+func (f EvidenceFilter) accept(code string) bool {
+	if f.include != nil && !f.include[code] {
+		return false
+	}
+	return !f.exclude[code]
+}
+
+// evidenceOf returns the evidence code held in the graph label of an
+// extended N-Quads annotation statement in the form:
+//
+//  <obo:GO_0000000> <local:annotates> <ensembl:ENSG00000000000> <evidence:IEA> .
+//
+// If the statement has no evidence label, the empty string is returned.
+func evidenceOf(s *rdf.Statement) string {
+	if !strings.HasPrefix(s.Label.Value, "<evidence:") {
+		return ""
+	}
+	return strip(s.Label.Value, "<evidence:", ">")
+}
+
+// isNegated returns whether an extended N-Quads annotation statement is
+// negated by a graph label in the form:
+//
+//  <obo:GO_0000000> <local:annotates> <ensembl:ENSG00000000000> <not:IEA> .
+func isNegated(s *rdf.Statement) bool {
+	return strings.HasPrefix(s.Label.Value, "<not:")
+}
@@ -0,0 +1,197 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+
+	"github.com/kortschak/gogo"
+)
+
+var evidenceFilterTests = []struct {
+	include, exclude string
+	accept           []string
+	reject           []string
+}{
+	{
+		accept: []string{"", "IDA", "IEA", "ND"},
+	},
+	{
+		include: "IDA,IMP",
+		accept:  []string{"IDA", "IMP"},
+		reject:  []string{"", "IEA", "IPI"},
+	},
+	{
+		include: " ida , imp ",
+		accept:  []string{"IDA", "IMP"},
+		reject:  []string{"", "IEA"},
+	},
+	{
+		exclude: "IEA",
+		accept:  []string{"", "IDA", "ND"},
+		reject:  []string{"IEA"},
+	},
+	{
+		include: "experimental",
+		accept:  []string{"EXP", "IDA", "IPI", "IMP", "IGI", "IEP"},
+		reject:  []string{"", "HDA", "IEA", "TAS"},
+	},
+	{
+		exclude: "Electronic,curator-statement",
+		accept:  []string{"", "IDA", "TAS"},
+		reject:  []string{"IEA", "IC", "ND"},
+	},
+	{
+		include: "experimental,high-throughput",
+		exclude: "IEP,HEP",
+		accept:  []string{"IDA", "HDA"},
+		reject:  []string{"IEP", "HEP", "IEA"},
+	},
+}
+
+func TestEvidenceFilter(t *testing.T) {
+	for _, test := range evidenceFilterTests {
+		f := NewEvidenceFilter(test.include, test.exclude)
+		for _, code := range test.accept {
+			if !f.accept(code) {
+				t.Errorf("unexpected rejection of %q with include=%q exclude=%q", code, test.include, test.exclude)
+			}
+		}
+		for _, code := range test.reject {
+			if f.accept(code) {
+				t.Errorf("unexpected acceptance of %q with include=%q exclude=%q", code, test.include, test.exclude)
+			}
+		}
+	}
+}
+
+var evidenceOfTests = []struct {
+	label string
+	want  string
+}{
+	{label: "", want: ""},
+	{label: "<evidence:IEA>", want: "IEA"},
+	{label: "<evidence:IDA>", want: "IDA"},
+	{label: "<local:graph>", want: ""},
+}
+
+func TestEvidenceOf(t *testing.T) {
+	for _, test := range evidenceOfTests {
+		s := &rdf.Statement{
+			Subject:   rdf.Term{Value: "<obo:GO_0003674>"},
+			Predicate: rdf.Term{Value: "<local:annotates>"},
+			Object:    rdf.Term{Value: "<ensembl:ENSG00000000001>"},
+			Label:     rdf.Term{Value: test.label},
+		}
+		got := evidenceOf(s)
+		if got != test.want {
+			t.Errorf("unexpected evidence for label %q: got:%q want:%q", test.label, got, test.want)
+		}
+	}
+}
+
+const (
+	testEvidenceQuads = `<obo:GO_0003674> <local:annotates> <ensembl:ENSG00000000001> <evidence:IDA> .
+<obo:GO_0005575> <local:annotates> <ensembl:ENSG00000000001> <evidence:IEA> .
+<obo:GO_0008150> <local:annotates> <ensembl:ENSG00000000002> .
+<obo:GO_0003674> <local:annotates> <ensembl:ENSG00000000002> <not:IDA> .
+<obo:GO_0005575> <local:other> <ensembl:ENSG00000000002> <evidence:IDA> .
+`
+
+	testEvidenceGAF = `!gaf-version: 2.2
+ENSEMBL	ENSG00000000001	GENE1	enables	GO:0003674	PMID:1	IDA		F			protein	taxon:9606	20210101	Ensembl		
+ENSEMBL	ENSG00000000001	GENE1	located_in	GO:0005575	GO_REF:1	IEA		C			protein	taxon:9606	20210101	Ensembl		
+ENSEMBL	ENSG00000000002	GENE2	involved_in	GO:0008150	PMID:1	IMP		P			protein	taxon:9606	20210101	Ensembl		
+ENSEMBL	ENSG00000000002	GENE2	NOT|enables	GO:0003674	PMID:1	IDA		F			protein	taxon:9606	20210101	Ensembl		
+`
+)
+
+var annotateEvidenceTests = []struct {
+	name             string
+	doc              string
+	include, exclude string
+	want             []string
+}{
+	{
+		name: "quads",
+		doc:  testEvidenceQuads,
+		want: []string{
+			"<obo:GO_0003674> <local:annotates> <ensembl:ENSG00000000001>",
+			"<obo:GO_0005575> <local:annotates> <ensembl:ENSG00000000001>",
+			"<obo:GO_0008150> <local:annotates> <ensembl:ENSG00000000002>",
+		},
+	},
+	{
+		name:    "quads exclude",
+		doc:     testEvidenceQuads,
+		exclude: "electronic",
+		want: []string{
+			"<obo:GO_0003674> <local:annotates> <ensembl:ENSG00000000001>",
+			"<obo:GO_0008150> <local:annotates> <ensembl:ENSG00000000002>",
+		},
+	},
+	{
+		name:    "quads include",
+		doc:     testEvidenceQuads,
+		include: "IDA",
+		want: []string{
+			"<obo:GO_0003674> <local:annotates> <ensembl:ENSG00000000001>",
+		},
+	},
+	{
+		name: "gaf",
+		doc:  testEvidenceGAF,
+		want: []string{
+			"<obo:GO_0003674> <local:annotates> <ensembl:ENSG00000000001>",
+			"<obo:GO_0005575> <local:annotates> <ensembl:ENSG00000000001>",
+			"<obo:GO_0008150> <local:annotates> <ensembl:ENSG00000000002>",
+		},
+	},
+	{
+		name:    "gaf exclude",
+		doc:     testEvidenceGAF,
+		exclude: "IEA",
+		want: []string{
+			"<obo:GO_0003674> <local:annotates> <ensembl:ENSG00000000001>",
+			"<obo:GO_0008150> <local:annotates> <ensembl:ENSG00000000002>",
+		},
+	},
+	{
+		name:    "gaf include",
+		doc:     testEvidenceGAF,
+		include: "experimental",
+		exclude: "IDA",
+		want: []string{
+			"<obo:GO_0008150> <local:annotates> <ensembl:ENSG00000000002>",
+		},
+	},
+}
+
+func TestAnnotateEvidence(t *testing.T) {
+	data := &CountData{
+		Names: []string{"S1"},
+		Counts: map[string][]float64{
+			"ENSG00000000001": {1},
+			"ENSG00000000002": {2},
+		},
+	}
+	for _, test := range annotateEvidenceTests {
+		o := &Ontology{graph: gogo.NewGraph()}
+		err := Annotate(context.Background(), o, strings.NewReader(test.doc), data, NewEvidenceFilter(test.include, test.exclude))
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", test.name, err)
+			continue
+		}
+		got := statementsOf(o.graph)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected annotations for %q:\ngot: %q\nwant:%q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+
+	"github.com/kortschak/gogo"
+)
+
+// gafAnnotation is a single GO annotation line from a GAF 2.x file.
+// See http://geneontology.org/docs/go-annotation-file-gaf-format-2.2/.
+type gafAnnotation struct {
+	objectID  string
+	symbol    string
+	qualifier []string
+	goID      string
+	evidence  string
+	synonyms  []string
+}
+
+// gafColumns is the number of tab-delimited columns in a GAF 2.x line.
+const gafColumns = 17
+
+// gafHeader is the start of the GAF version header line.
+const gafHeader = "!gaf-version:"
+
+// isGAF returns whether the stream in r starts with a GAF version header.
+// The reader is not advanced.
+func isGAF(r *bufio.Reader) bool {
+	b, _ := r.Peek(len(gafHeader))
+	return string(b) == gafHeader
+}
+
+// checkGAFVersion returns an error if the GAF version header line is not
+// for a GAF 2.x file.
+func checkGAFVersion(line string) error {
+	if !strings.HasPrefix(line, gafHeader) {
+		return errors.New("missing GAF version header")
+	}
+	v := strings.TrimSpace(strings.TrimPrefix(line, gafHeader))
+	if v != "2" && !strings.HasPrefix(v, "2.") {
+		return fmt.Errorf("unsupported GAF version: %q", v)
+	}
+	return nil
+}
+
+// parseGAFLine parses a single non-comment GAF 2.x line.
+func parseGAFLine(line string) (gafAnnotation, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != gafColumns {
+		return gafAnnotation{}, fmt.Errorf("unexpected number of GAF columns: %d != %d", len(fields), gafColumns)
+	}
+	return gafAnnotation{
+		objectID:  fields[1],
+		symbol:    fields[2],
+		qualifier: splitNonEmpty(fields[3], "|"),
+		goID:      fields[4],
+		evidence:  fields[6],
+		synonyms:  splitNonEmpty(fields[10], "|"),
+	}, nil
+}
+
+// geneIDIn returns the first identifier associated with the annotation
+// that is a key in counts. The DB Object ID is checked first, followed by
+// the DB Object Symbol and then the DB Object Synonyms. Identifiers are
+// checked both verbatim and with any "DB:" prefix removed.
+func (a gafAnnotation) geneIDIn(counts map[string][]float64) (id string, ok bool) {
+	candidates := append([]string{a.objectID, a.symbol}, a.synonyms...)
+	for _, c := range candidates {
+		if _, ok := counts[c]; ok {
+			return c, true
+		}
+		if i := strings.Index(c, ":"); i >= 0 {
+			c = c[i+1:]
+			if _, ok := counts[c]; ok {
+				return c, true
+			}
+		}
+	}
+	return "", false
+}
+
+// isNegated returns whether the annotation has a NOT qualifier.
+func (a gafAnnotation) isNegated() bool {
+	for _, q := range a.qualifier {
+		if q == "NOT" {
+			return true
+		}
+	}
+	return false
+}
+
+// statement returns the annotation statement for the annotation and the
+// provided gene identifier in the same form as is used for N-Triples and
+// N-Quads annotation input.
+func (a gafAnnotation) statement(geneID string) (*rdf.Statement, error) {
+	if !strings.HasPrefix(a.goID, "GO:") {
+		return nil, fmt.Errorf("invalid GO ID: %q", a.goID)
+	}
+	return &rdf.Statement{
+		Subject:   rdf.Term{Value: "<obo:GO_" + strings.TrimPrefix(a.goID, "GO:") + ">"},
+		Predicate: rdf.Term{Value: "<local:annotates>"},
+		Object:    rdf.Term{Value: "<ensembl:" + geneID + ">"},
+	}, nil
+}
+
+// connectGAF adds the annotations in the GAF 2.x stream in r to the
+// destination graph. The stream must start with a GAF 2.x version header.
+// Only annotations that can be mapped to genes in the
+// counts map and that are accepted by the evidence filter are added to
+// the graph. Annotations with a NOT qualifier are never added.
+func connectGAF(ctx context.Context, dst *gogo.Graph, r io.Reader, counts map[string][]float64, filter EvidenceFilter) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	var n int
+	for sc.Scan() {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		n++
+		line := sc.Bytes()
+		if n == 1 {
+			err = checkGAFVersion(string(line))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if len(bytes.TrimSpace(line)) == 0 || line[0] == '!' {
+			continue
+		}
+		a, err := parseGAFLine(string(line))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if a.isNegated() || !filter.accept(a.evidence) {
+			continue
+		}
+
+		// Only keep annotations needed for the given counts.
+		id, ok := a.geneIDIn(counts)
+		if !ok {
+			continue
+		}
+
+		s, err := a.statement(id)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		dst.AddStatement(s)
+	}
+	return sc.Err()
+}
+
+func splitNonEmpty(s, sep string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, sep)
+}
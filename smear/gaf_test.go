@@ -0,0 +1,267 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"bufio"
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kortschak/gogo"
+)
+
+var isGAFTests = []struct {
+	doc  string
+	want bool
+}{
+	{doc: "!gaf-version: 2.2\n", want: true},
+	{doc: "!gaf-version: 2.1\n!generated-by: GOC\n", want: true},
+	{doc: "!gaf-version:", want: true},
+	{doc: "!gaf-version", want: false},
+	{doc: "", want: false},
+	{doc: "!generated-by: GOC\n!gaf-version: 2.2\n", want: false},
+	{doc: "<obo:GO_0000001> <local:annotates> <ensembl:ENSG00000000001> .\n", want: false},
+}
+
+func TestIsGAF(t *testing.T) {
+	for _, test := range isGAFTests {
+		br := bufio.NewReader(strings.NewReader(test.doc))
+		got := isGAF(br)
+		if got != test.want {
+			t.Errorf("unexpected result for %q: got:%t want:%t", test.doc, got, test.want)
+		}
+		rest, _ := br.Peek(len(test.doc))
+		if string(rest) != test.doc {
+			t.Errorf("reader advanced for %q: remaining:%q", test.doc, rest)
+		}
+	}
+}
+
+var parseGAFLineTests = []struct {
+	name    string
+	line    string
+	want    gafAnnotation
+	wantErr bool
+}{
+	{
+		name: "simple",
+		line: "UniProtKB\tP12345\tGENE1\tenables\tGO:0003674\tPMID:1\tIDA\t\tF\tgene one\tENSG00000000001|G1\tprotein\ttaxon:9606\t20210101\tUniProt\t\t",
+		want: gafAnnotation{
+			objectID:  "P12345",
+			symbol:    "GENE1",
+			qualifier: []string{"enables"},
+			goID:      "GO:0003674",
+			evidence:  "IDA",
+			synonyms:  []string{"ENSG00000000001", "G1"},
+		},
+	},
+	{
+		name: "negated",
+		line: "UniProtKB\tP12345\tGENE1\tNOT|involved_in\tGO:0008150\tPMID:1\tIMP\t\tP\t\t\tprotein\ttaxon:9606\t20210101\tUniProt\t\t",
+		want: gafAnnotation{
+			objectID:  "P12345",
+			symbol:    "GENE1",
+			qualifier: []string{"NOT", "involved_in"},
+			goID:      "GO:0008150",
+			evidence:  "IMP",
+		},
+	},
+	{
+		name: "no qualifier",
+		line: "ENSEMBL\tENSG00000000002\tGENE2\t\tGO:0005575\tGO_REF:1\tIEA\t\tC\t\t\tgene\ttaxon:9606\t20210101\tEnsembl\t\t",
+		want: gafAnnotation{
+			objectID: "ENSG00000000002",
+			symbol:   "GENE2",
+			goID:     "GO:0005575",
+			evidence: "IEA",
+		},
+	},
+	{
+		name:    "short line",
+		line:    "UniProtKB\tP12345\tGENE1\tenables\tGO:0003674\tPMID:1\tIDA",
+		wantErr: true,
+	},
+	{
+		name:    "long line",
+		line:    "UniProtKB\tP12345\tGENE1\tenables\tGO:0003674\tPMID:1\tIDA\t\tF\t\t\tprotein\ttaxon:9606\t20210101\tUniProt\t\t\t",
+		wantErr: true,
+	},
+}
+
+func TestParseGAFLine(t *testing.T) {
+	for _, test := range parseGAFLineTests {
+		got, err := parseGAFLine(test.line)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %q: got:%v want error:%t", test.name, err, test.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected annotation for %q:\ngot: %#v\nwant:%#v", test.name, got, test.want)
+		}
+	}
+}
+
+var geneIDInTests = []struct {
+	name   string
+	a      gafAnnotation
+	want   string
+	wantOK bool
+}{
+	{
+		name:   "object id",
+		a:      gafAnnotation{objectID: "ENSG00000000001", symbol: "ENSG00000000002"},
+		want:   "ENSG00000000001",
+		wantOK: true,
+	},
+	{
+		name:   "prefixed object id",
+		a:      gafAnnotation{objectID: "ENSEMBL:ENSG00000000001"},
+		want:   "ENSG00000000001",
+		wantOK: true,
+	},
+	{
+		name:   "symbol",
+		a:      gafAnnotation{objectID: "P12345", symbol: "ENSG00000000002"},
+		want:   "ENSG00000000002",
+		wantOK: true,
+	},
+	{
+		name:   "synonym",
+		a:      gafAnnotation{objectID: "P12345", symbol: "GENE1", synonyms: []string{"G1", "ENSEMBL:ENSG00000000002"}},
+		want:   "ENSG00000000002",
+		wantOK: true,
+	},
+	{
+		name:   "symbol before synonym",
+		a:      gafAnnotation{objectID: "P12345", symbol: "ENSG00000000002", synonyms: []string{"ENSG00000000001"}},
+		want:   "ENSG00000000002",
+		wantOK: true,
+	},
+	{
+		name:   "absent",
+		a:      gafAnnotation{objectID: "P12345", symbol: "GENE1", synonyms: []string{"G1"}},
+		wantOK: false,
+	},
+}
+
+func TestGeneIDIn(t *testing.T) {
+	counts := map[string][]float64{
+		"ENSG00000000001": {1},
+		"ENSG00000000002": {2},
+	}
+	for _, test := range geneIDInTests {
+		got, ok := test.a.geneIDIn(counts)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("unexpected gene id for %q: got:%q,%t want:%q,%t", test.name, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+var connectGAFTests = []struct {
+	name    string
+	doc     string
+	want    []string
+	wantErr bool
+}{
+	{
+		name: "valid",
+		doc: `!gaf-version: 2.2
+!generated-by: test
+
+ENSEMBL	ENSG00000000001	GENE1	enables	GO:0003674	PMID:1	IDA		F			protein	taxon:9606	20210101	Ensembl		
+UniProtKB	P12345	GENE2	involved_in	GO:0008150	PMID:1	IMP		P		ENSG00000000002	protein	taxon:9606	20210101	UniProt		
+UniProtKB	P23456	GENE3	involved_in	GO:0008150	PMID:1	IMP		P		ENSG00000000009	protein	taxon:9606	20210101	UniProt		
+`,
+		want: []string{
+			"<obo:GO_0003674> <local:annotates> <ensembl:ENSG00000000001>",
+			"<obo:GO_0008150> <local:annotates> <ensembl:ENSG00000000002>",
+		},
+	},
+	{
+		name: "negated",
+		doc: `!gaf-version: 2.2
+ENSEMBL	ENSG00000000001	GENE1	NOT|enables	GO:0003674	PMID:1	IDA		F			protein	taxon:9606	20210101	Ensembl		
+ENSEMBL	ENSG00000000002	GENE2	located_in	GO:0005575	PMID:1	IDA		C			protein	taxon:9606	20210101	Ensembl		
+`,
+		want: []string{
+			"<obo:GO_0005575> <local:annotates> <ensembl:ENSG00000000002>",
+		},
+	},
+	{
+		name: "invalid GO ID",
+		doc: `!gaf-version: 2.2
+ENSEMBL	ENSG00000000001	GENE1	enables	0003674	PMID:1	IDA		F			protein	taxon:9606	20210101	Ensembl		
+`,
+		wantErr: true,
+	},
+	{
+		name: "version 2.1",
+		doc: `!gaf-version: 2.1
+ENSEMBL	ENSG00000000001	GENE1	enables	GO:0003674	PMID:1	IDA		F			protein	taxon:9606	20210101	Ensembl		
+`,
+		want: []string{
+			"<obo:GO_0003674> <local:annotates> <ensembl:ENSG00000000001>",
+		},
+	},
+	{
+		name: "unsupported version",
+		doc: `!gaf-version: 1.0
+UniProtKB	P12345	GENE1		GO:0003674	PMID:1	IDA		F			protein	taxon:9606	20210101	UniProt
+`,
+		wantErr: true,
+	},
+	{
+		name: "missing version",
+		doc: `ENSEMBL	ENSG00000000001	GENE1	enables	GO:0003674	PMID:1	IDA		F			protein	taxon:9606	20210101	Ensembl		
+`,
+		wantErr: true,
+	},
+	{
+		name: "invalid columns",
+		doc: `!gaf-version: 2.2
+ENSEMBL	ENSG00000000001	GENE1	enables	GO:0003674
+`,
+		wantErr: true,
+	},
+}
+
+func TestConnectGAF(t *testing.T) {
+	counts := map[string][]float64{
+		"ENSG00000000001": {1},
+		"ENSG00000000002": {2},
+	}
+	for _, test := range connectGAFTests {
+		g := gogo.NewGraph()
+		err := connectGAF(context.Background(), g, strings.NewReader(test.doc), counts, EvidenceFilter{})
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %q: got:%v want error:%t", test.name, err, test.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		got := statementsOf(g)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected annotations for %q:\ngot: %q\nwant:%q", test.name, got, test.want)
+		}
+	}
+}
+
+// statementsOf returns the statements in g without their labels, sorted
+// lexically.
+func statementsOf(g *gogo.Graph) []string {
+	var statements []string
+	it := g.AllStatements()
+	for it.Next() {
+		s := it.Statement()
+		statements = append(statements, s.Subject.Value+" "+s.Predicate.Value+" "+s.Object.Value)
+	}
+	sort.Strings(statements)
+	return statements
+}
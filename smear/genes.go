@@ -0,0 +1,74 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import "github.com/kortschak/smeargol/internal/bitset"
+
+// GeneSet is a read-only set of gene indices. The zero value is an empty
+// set.
+type GeneSet struct {
+	set *bitset.Set
+}
+
+// NewGeneSet returns a GeneSet holding the given gene indices.
+func NewGeneSet(genes ...int) GeneSet {
+	set := new(bitset.Set)
+	for _, g := range genes {
+		set.Set(g)
+	}
+	return GeneSet{set: set}
+}
+
+// bits returns the bit vector holding the genes of s.
+func (s GeneSet) bits() *bitset.Set {
+	if s.set == nil {
+		return &bitset.Set{}
+	}
+	return s.set
+}
+
+// Len returns the number of genes in s.
+func (s GeneSet) Len() int {
+	return s.bits().Count()
+}
+
+// Has returns whether gene is in s.
+func (s GeneSet) Has(gene int) bool {
+	return s.bits().Test(gene)
+}
+
+// Do calls fn with each gene in s in ascending order.
+func (s GeneSet) Do(fn func(gene int)) {
+	s.bits().Do(fn)
+}
+
+// Genes returns the genes in s in ascending order.
+func (s GeneSet) Genes() []int {
+	var genes []int
+	s.Do(func(g int) { genes = append(genes, g) })
+	return genes
+}
+
+// Binary returns the base 2 representation of s as a bit vector, with the
+// highest gene indices first, padded with zeros to at least width digits.
+func (s GeneSet) Binary(width int) string {
+	return s.bits().Binary(width)
+}
+
+// IntersectionLen returns the number of genes in both s and t without
+// constructing their intersection.
+func (s GeneSet) IntersectionLen(t GeneSet) int {
+	return s.bits().AndCount(t.bits())
+}
+
+// Intersect returns the genes in both s and t.
+func (s GeneSet) Intersect(t GeneSet) GeneSet {
+	return GeneSet{set: new(bitset.Set).And(s.bits(), t.bits())}
+}
+
+// Union returns the genes in either s or t.
+func (s GeneSet) Union(t GeneSet) GeneSet {
+	return GeneSet{set: new(bitset.Set).Or(s.bits(), t.bits())}
+}
@@ -0,0 +1,48 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"reflect"
+	"testing"
+)
+
+var geneSetTests = []struct {
+	name      string
+	x, y      []int
+	intersect []int
+	union     []int
+}{
+	{name: "empty"},
+	{name: "left empty", y: []int{1, 2}, union: []int{1, 2}},
+	{name: "disjoint", x: []int{0, 4}, y: []int{1, 70000}, union: []int{0, 1, 4, 70000}},
+	{name: "overlapping", x: []int{3, 1, 2}, y: []int{2, 3, 4}, intersect: []int{2, 3}, union: []int{1, 2, 3, 4}},
+}
+
+func TestGeneSet(t *testing.T) {
+	for _, test := range geneSetTests {
+		x := NewGeneSet(test.x...)
+		y := NewGeneSet(test.y...)
+		if got := x.Intersect(y).Genes(); !reflect.DeepEqual(got, test.intersect) {
+			t.Errorf("unexpected intersection for %s: got:%v want:%v", test.name, got, test.intersect)
+		}
+		if got := x.IntersectionLen(y); got != len(test.intersect) {
+			t.Errorf("unexpected intersection length for %s: got:%d want:%d", test.name, got, len(test.intersect))
+		}
+		if got := x.Union(y).Genes(); !reflect.DeepEqual(got, test.union) {
+			t.Errorf("unexpected union for %s: got:%v want:%v", test.name, got, test.union)
+		}
+		for _, g := range test.x {
+			if !x.Has(g) {
+				t.Errorf("missing gene %d for %s", g, test.name)
+			}
+		}
+	}
+
+	var zero GeneSet
+	if zero.Len() != 0 || zero.Has(0) || zero.Genes() != nil {
+		t.Error("unexpected members of zero GeneSet")
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright ©2020 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/formats/rdf"
+	"gonum.org/v1/gonum/graph/traverse"
+
+	"github.com/kortschak/gogo"
+	"github.com/kortschak/smeargol/internal/obo"
+	"github.com/kortschak/smeargol/owl"
+)
+
+// Ontology is a Gene Ontology DAG that count data can be painted onto.
+type Ontology struct {
+	graph *gogo.Graph
+	rels  Relations
+	anc   *ancestry
+}
+
+// LoadOntology returns the ontology stored in OBO in OWL or OBO 1.4 flat
+// file format in r. The format is determined from the content of r. The
+// namespaces are not expanded to full IRI namespaces. If lean is true,
+// only the statements needed for painting are retained. Existential
+// restrictions on the relations in rels other than is_a are added to the
+// ontology as direct statements between GO terms in the same namespace,
+// and traversals of the ontology follow the relations in rels. Restrictions
+// between terms in different namespaces, such as a biological process that
+// is part_of a cellular component, are not followed so that the aspects of
+// the ontology remain unconnected. Loading stops if ctx is cancelled
+// and the context's error is returned.
+func LoadOntology(ctx context.Context, r io.Reader, lean bool, rels Relations) (*Ontology, error) {
+	br := bufio.NewReader(r)
+
+	g := gogo.NewGraph()
+	var err error
+	var dec interface {
+		UnmarshalLocal() (*rdf.Statement, error)
+	}
+	if isXML(br) {
+		var opts owl.Options
+		if lean {
+			// Axioms and the ontology header never hold
+			// statements used for painting, and statements
+			// that are neither used nor needed to reconstruct
+			// restrictions are dropped by the decoder before
+			// they are retained.
+			opts.Elements = owl.AllElements &^ (owl.Axiom | owl.Ontology)
+			opts.Filter = isLeanStatement
+		}
+		dec, err = owl.NewDecoderOptions(br, opts)
+	} else {
+		dec, err = obo.NewDecoder(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+	restrictions := newRestrictions()
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+		s, err := dec.UnmarshalLocal()
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		restrictions.record(s)
+
+		if lean {
+			// Filter on statements that are actually used. The filter
+			// can be more restrictive since we only need "GO subclass GO"
+			// and "GO hasOBONamespace literal" statements, but this is
+			// good enough.
+			switch s.Predicate.Value {
+			// This list must include all predicates used in traversals
+			// except <local:annotates> which comes from Annotate
+			// and the relation predicates which are reconstructed from
+			// restrictions below.
+			case "<rdfs:subClassOf>", "<oboInOwl:hasOBONamespace>":
+			default:
+				continue
+			}
+		}
+
+		g.AddStatement(s)
+	}
+	restrictions.addTo(g, rels)
+
+	return &Ontology{graph: g, rels: rels, anc: newAncestry(g, rels)}, nil
+}
+
+// leanPredicates is the set of full IRI predicates of statements needed
+// for painting or for reconstructing restrictions when an ontology is
+// loaded in lean mode.
+var leanPredicates = map[string]bool{
+	"<http://www.w3.org/2000/01/rdf-schema#subClassOf>":              true,
+	"<http://www.geneontology.org/formats/oboInOwl#hasOBONamespace>": true,
+	"<http://www.w3.org/2002/07/owl#onProperty>":                     true,
+	"<http://www.w3.org/2002/07/owl#someValuesFrom>":                 true,
+}
+
+// isLeanStatement returns whether s is retained in lean mode.
+func isLeanStatement(s *rdf.Statement) bool {
+	return leanPredicates[s.Predicate.Value]
+}
+
+// Graph returns the graph of the ontology, including any annotations
+// that have been added to it.
+func (o *Ontology) Graph() *gogo.Graph {
+	return o.graph
+}
+
+// Namespace returns the ontology aspect for the term with the IRI term,
+// which is expected to be an <obo:GO_*> term. If the term has no namespace,
+// "NA" is returned and if it has more than one or its namespace is not a
+// literal, the term's IRI is returned.
+func (o *Ontology) Namespace(term string) string {
+	t, ok := o.graph.TermFor(term)
+	if !ok {
+		return "NA"
+	}
+	ns := o.graph.Query(t).Out(func(s *rdf.Statement) bool {
+		return s.Predicate.Value == "<oboInOwl:hasOBONamespace>"
+	}).Result()
+	switch len(ns) {
+	case 0:
+		return "NA"
+	case 1:
+		text, _, kind, err := ns[0].Parts()
+		if err != nil {
+			panic(fmt.Errorf("invalid term in graph: %w", err))
+		}
+		if kind == rdf.Literal {
+			return text
+		}
+		return t.Value
+	default:
+		return t.Value
+	}
+}
+
+// Roots returns the roots of the ontology sorted by their IRI.
+func (o *Ontology) Roots() []rdf.Term {
+	roots := o.graph.Roots(false)
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Value < roots[j].Value })
+	return roots
+}
+
+// isXML returns whether the first non-space byte in r is the start of
+// an XML element. The reader is not advanced.
+func isXML(r *bufio.Reader) bool {
+	return firstNonSpaceIs(r, '<')
+}
+
+// firstNonSpaceIs returns whether the first non-space byte in r is b.
+// The reader is not advanced.
+func firstNonSpaceIs(r *bufio.Reader, b byte) bool {
+	for n := 1; ; n++ {
+		p, err := r.Peek(n)
+		if len(p) < n {
+			return false
+		}
+		switch c := p[n-1]; c {
+		case ' ', '\t', '\n', '\r':
+			if err != nil {
+				return false
+			}
+		default:
+			return c == b
+		}
+	}
+}
+
+// Annotate adds the annotation statements in r to the ontology. The
+// statements are expected to have local IRI namespaces and be in the
+// following form:
+//
+//   <obo:GO_0000000> <local:annotates> <ensembl:ENSG00000000000> .
+//
+// The N-Quads form may carry the evidence code for the annotation in the
+// graph label:
+//
+//   <obo:GO_0000000> <local:annotates> <ensembl:ENSG00000000000> <evidence:IEA> .
+//
+// A negated annotation is marked by a graph label in the form:
+//
+//   <obo:GO_0000000> <local:annotates> <ensembl:ENSG00000000000> <not:IEA> .
+//
+// Alternatively, r may hold a GAF 2.1 or 2.2 annotation file, which
+// is detected by its !gaf-version header. In this case the annotations
+// are converted to the form above using the first of the DB Object ID,
+// DB Object Symbol or DB Object Synonyms that matches a gene identifier
+// in data.
+//
+// Only ENSG identifiers that match the genes in data and annotations
+// accepted by filter are added to the ontology. Statements with a predicate
+// other than <local:annotates> and negated annotations are never added.
+// Reading stops if ctx is cancelled and the context's error is returned.
+func Annotate(ctx context.Context, o *Ontology, r io.Reader, data *CountData, filter EvidenceFilter) error {
+	dst := o.graph
+	counts := data.Counts
+	br := bufio.NewReader(r)
+	if isGAF(br) {
+		return connectGAF(ctx, dst, br, counts, filter)
+	}
+
+	dec := rdf.NewDecoder(br)
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		s, err := dec.Unmarshal()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if s.Predicate.Value != "<local:annotates>" || isNegated(s) {
+			continue
+		}
+
+		// Only keep annotations needed for the given counts.
+		id := strip(s.Object.Value, "<ensembl:", ">")
+		if _, ok := counts[id]; !ok {
+			continue
+		}
+		if !filter.accept(evidenceOf(s)) {
+			continue
+		}
+
+		s.Subject.UID = 0
+		s.Predicate.UID = 0
+		s.Object.UID = 0
+		s.Label = rdf.Term{}
+		dst.AddStatement(s)
+	}
+}
+
+// leafiestFor return the leaf-most terms for gene from each of the ontology roots
+// with respect to the relations indexed by anc. The leaf sets are returned separated so
+// that ontology count mutation can be performed per root. If no GO term is
+// annotated to the gene, found is returned false.
+func leafiestFor(geneid string, g *gogo.Graph, roots []rdf.Term, anc *ancestry) (leafiest [][]rdf.Term, found bool) {
+	leafiest = make([][]rdf.Term, len(roots))
+	from, ok := g.TermFor("<ensembl:" + geneid + ">")
+	if !ok {
+		return leafiest, false
+	}
+
+	terms := g.Query(from).In(func(s *rdf.Statement) bool {
+		return s.Predicate.Value == "<local:annotates>"
+	}).Unique().Result()
+	if len(terms) == 0 {
+		return leafiest, false
+	}
+
+	for a, r := range roots {
+		var depths []gogo.Descendant
+		for _, q := range terms {
+			ok, d := anc.isDescendantOf(r, q)
+			if ok {
+				depths = append(depths, gogo.Descendant{Term: q, Depth: d})
+			}
+		}
+		sort.Sort(byDepth(depths))
+
+		for i := 0; i < len(depths); i++ {
+			a := depths[i]
+			for j := i + 1; j < len(depths); {
+				ok, _ := anc.isDescendantOf(a.Term, depths[j].Term)
+				if ok {
+					copy(depths[j:], depths[j+1:])
+					depths = depths[:len(depths)-1]
+				} else {
+					j++
+				}
+			}
+		}
+		for _, d := range depths {
+			leafiest[a] = append(leafiest[a], d.Term)
+		}
+	}
+	return leafiest, true
+}
+
+// byDepth sorts gogo.Descendents by depth, leafiest first.
+type byDepth []gogo.Descendant
+
+func (d byDepth) Len() int           { return len(d) }
+func (d byDepth) Less(i, j int) bool { return d[i].Depth > d[j].Depth }
+func (d byDepth) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// walkDownSubClassesFrom performs a breadth-first enumeration of GO subclass
+// terms in g starting from r, and calling fn for each term, including r.
+// Terms are related by the relations in rels. The walk is stopped if ctx is
+// cancelled, and the context's error is returned.
+func walkDownSubClassesFrom(ctx context.Context, r rdf.Term, g *gogo.Graph, rels Relations, fn func(root, term rdf.Term, depth int)) error {
+	bf := traverse.BreadthFirst{Traverse: rels.goIsSubClassOf}
+	bf.Walk(reverse{g}, r, func(n graph.Node, d int) bool {
+		if ctx.Err() != nil {
+			return true
+		}
+		fn(r, n.(rdf.Term), d)
+		return false
+	})
+	return ctx.Err()
+}
+
+// Level is a level of the GO DAG below a root.
+type Level struct {
+	// Depth is the distance of the level
+	// from the root.
+	Depth int
+
+	// Terms holds the IRIs of the terms in
+	// the level in the order they were
+	// reached in a breadth-first walk.
+	Terms []string
+}
+
+// Levels returns the levels of the ontology below root, including the
+// level holding root, in order of increasing depth. The walk is stopped if
+// ctx is cancelled and the context's error is returned.
+func Levels(ctx context.Context, o *Ontology, root rdf.Term) ([]Level, error) {
+	var levels []Level
+	err := walkDownSubClassesFrom(ctx, root, o.graph, o.rels, func(_, t rdf.Term, d int) {
+		if len(levels) == 0 || levels[len(levels)-1].Depth != d {
+			levels = append(levels, Level{Depth: d})
+		}
+		l := &levels[len(levels)-1]
+		l.Terms = append(l.Terms, t.Value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// reverse implements the traverse.Graph reversing the direction of edges.
+type reverse struct {
+	*gogo.Graph
+}
+
+func (g reverse) From(id int64) graph.Nodes      { return g.Graph.To(id) }
+func (g reverse) Edge(uid, vid int64) graph.Edge { return g.Graph.Edge(vid, uid) }
+
+func strip(s, prefix, suffix string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(s, prefix), suffix)
+}
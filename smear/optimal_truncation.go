@@ -0,0 +1,452 @@
+// Copyright ©2020 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/integrate/quad"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Summary is the summary of the rank analysis of a level matrix.
+type Summary struct {
+	// Name is the name of the sample.
+	Name string
+
+	// Root is the root GO term for the summary.
+	Root string
+
+	// Depth is the distance from the root.
+	Depth int
+
+	// Rows and Cols are the dimensions of the matrix
+	// describing the GO level. Rows corresponds to the
+	// number of genes and Cols corresponds to the number
+	// of GO terms in the level.
+	Rows, Cols int
+
+	// OptimalRank and FractionalRank are the calculated
+	// ranks of the summary matrix. OptimalRank is
+	// calculated according to the method of Matan Gavish
+	// and David L. Donoho https://arxiv.org/abs/1305.5870.
+	// FractionalRank is the rank calculated using the
+	// user-provided fraction parameters.
+	OptimalRank, FractionalRank int
+
+	// Threshold is the method used to calculate the
+	// singular value threshold for OptimalRank, one of
+	// unknown-noise, known-noise or marchenko-pastur.
+	// Tau is the threshold value and Noise is the
+	// noise level used to calculate it, or estimated
+	// from the singular values if not provided.
+	Threshold  string
+	Tau, Noise float64
+
+	// Bootstrap is the number of bootstrap replicates,
+	// formed by resampling genes with replacement, used
+	// to estimate the variability of OptimalRank. If it
+	// is zero, no bootstrap was performed. RankMean and
+	// RankStdDev are the mean and standard deviation of
+	// the replicate optimal ranks and RankCI holds their
+	// 2.5th and 97.5th percentiles.
+	Bootstrap            int
+	RankMean, RankStdDev float64
+	RankCI               [2]float64
+
+	// Sigma is the set of singular values. It is the
+	// complete set unless SigmaPartial is true.
+	Sigma []float64
+
+	// SigmaPartial indicates that Sigma holds only the
+	// largest singular values, calculated by randomized
+	// truncated SVD since the matrix exceeded the size
+	// limit for a full SVD. In this case the noise level
+	// is estimated from the residual energy of the matrix
+	// not accounted for by Sigma, rather than from the
+	// median singular value, and FractionalRank is
+	// calculated using the sum of the missing singular
+	// values implied by that noise level.
+	SigmaPartial bool
+}
+
+// RankOptions holds the parameters for a rank analysis.
+type RankOptions struct {
+	// Cut is the minimum valid singular value.
+	Cut float64
+
+	// Frac is the cumulative fraction of the
+	// singular values used to calculate the
+	// FractionalRank.
+	Frac float64
+
+	// Threshold is the singular value threshold
+	// used to calculate the OptimalRank.
+	Threshold Threshold
+
+	// SVDLimit is the maximum number of matrix
+	// elements for a full SVD. Only the largest
+	// SVDRank singular values of larger matrices
+	// are calculated.
+	SVDLimit, SVDRank int
+
+	// Bootstrap is the number of bootstrap
+	// replicates used to estimate the variability
+	// of the optimal rank. No bootstrap is
+	// performed if it is zero.
+	Bootstrap int
+}
+
+// Rank returns the summary of the optimal truncation of m calculated
+// according to the method of Matan Gavish and David L. Donoho
+// https://arxiv.org/abs/1305.5870 using the parameters in opts. The
+// Name, Root and Depth fields of the returned summary are not set. If the
+// bootstrap fails, the summary is returned with the error.
+func Rank(m *Matrix, opts RankOptions) (*Summary, error) {
+	rows, cols := m.Dims()
+	sigma, partial, err := singularValues(m, opts.SVDLimit, opts.SVDRank)
+	if err != nil {
+		return nil, fmt.Errorf("could not factorise matrix: %w", err)
+	}
+	var resid *residual
+	if partial {
+		resid = residualOf(m, sigma)
+	}
+
+	sum := make([]float64, len(sigma))
+	floats.CumSum(sum, sigma)
+	var rFrac int
+	max := sum[len(sum)-1]
+	if resid != nil {
+		max += resid.sum(rows, cols)
+	}
+	if max != 0 {
+		floats.Scale(1/max, sum)
+		rFrac = idxAbove(opts.Frac, sum)
+	}
+
+	sigmaCut := CutValues(sigma, opts.Cut)
+
+	thresh := opts.Threshold
+	t, noise := thresh.tau(rows, cols, sigmaCut, resid)
+	rOpt := idxBelow(t, sigmaCut)
+
+	s := &Summary{Rows: rows, Cols: cols, OptimalRank: rOpt, FractionalRank: rFrac, Threshold: thresh.method, Tau: t, Noise: noise, Sigma: sigma, SigmaPartial: partial}
+	if opts.Bootstrap > 0 {
+		ranks, err := bootstrapRanks(m, opts.Bootstrap, opts.Cut, thresh, opts.SVDLimit, opts.SVDRank)
+		if err != nil {
+			return s, fmt.Errorf("could not bootstrap matrix: %w", err)
+		}
+		s.Bootstrap = opts.Bootstrap
+		s.RankMean, s.RankStdDev = stat.MeanStdDev(ranks, nil)
+		sort.Float64s(ranks)
+		s.RankCI = [2]float64{
+			stat.Quantile(0.025, stat.Empirical, ranks, nil),
+			stat.Quantile(0.975, stat.Empirical, ranks, nil),
+		}
+	}
+
+	return s, nil
+}
+
+// CutValues returns the prefix of the singular values in sigma, sorted in
+// descending order, that are not less than cut.
+func CutValues(sigma []float64, cut float64) []float64 {
+	return sigma[:idxBelow(cut, sigma)]
+}
+
+// FractionalValue returns the singular value at the fractional rank of s.
+func (s *Summary) FractionalValue() float64 {
+	switch {
+	case s.FractionalRank < len(s.Sigma):
+		return s.Sigma[s.FractionalRank]
+	case len(s.Sigma) != 0:
+		return s.Sigma[0]
+	default:
+		return 0
+	}
+}
+
+// singularValues returns the singular values of m in descending order. If
+// m has more than limit elements, only the largest rank singular values are
+// calculated and partial is returned true.
+func singularValues(m *Matrix, limit, rank int) (sigma []float64, partial bool, err error) {
+	rows, cols := m.Dims()
+	if rows*cols > limit && rank < min(rows, cols) {
+		sigma, err = truncatedValues(m, rank)
+		return sigma, true, err
+	}
+	var svd mat.SVD
+	ok := svd.Factorize(m.dense(), mat.SVDThin)
+	if !ok {
+		return nil, false, errors.New("svd failed")
+	}
+	return svd.Values(nil), false, nil
+}
+
+// residual describes the singular values of a matrix that are not
+// calculated by a truncated SVD.
+type residual struct {
+	// values is the number of singular values
+	// that were not calculated and energy is the
+	// sum of their squares.
+	values int
+	energy float64
+}
+
+// residualOf returns the residual of the singular values of m that are not
+// held in sigma, the largest singular values of m.
+func residualOf(m *Matrix, sigma []float64) *residual {
+	rows, cols := m.Dims()
+	energy := m.sumSquares() - floats.Dot(sigma, sigma)
+	if energy < 0 {
+		// Allow for rounding error.
+		energy = 0
+	}
+	return &residual{values: min(rows, cols) - len(sigma), energy: energy}
+}
+
+// noise returns the noise level of a rows×cols matrix implied by the
+// residual, assuming that the residual singular values are the smallest
+// singular values of a Marchenko-Pastur distributed noise matrix.
+func (r *residual) noise(rows, cols int) float64 {
+	if r.values <= 0 || r.energy == 0 {
+		return 0
+	}
+	beta := aspect(rows, cols)
+	energy, _ := mpLowerMoments(beta, float64(r.values)/float64(min(rows, cols)))
+	return math.Sqrt(r.energy / (float64(rows) * float64(cols) * energy))
+}
+
+// sum returns the estimated sum of the residual singular values of a
+// rows×cols matrix.
+func (r *residual) sum(rows, cols int) float64 {
+	noise := r.noise(rows, cols)
+	if noise == 0 {
+		return 0
+	}
+	beta := aspect(rows, cols)
+	_, mean := mpLowerMoments(beta, float64(r.values)/float64(min(rows, cols)))
+	return float64(min(rows, cols)) * math.Sqrt(float64(max(rows, cols))) * noise * mean
+}
+
+func idxAbove(thresh float64, s []float64) int {
+	for i, v := range s {
+		if v > thresh {
+			return i
+		}
+	}
+	return len(s)
+}
+
+func idxBelow(thresh float64, s []float64) int {
+	for i, v := range s {
+		if v < thresh {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// Singular value threshold methods.
+const (
+	// UnknownNoise is the Gavish and Donoho
+	// threshold for unknown noise level.
+	UnknownNoise = "unknown-noise"
+
+	// KnownNoise is the Gavish and Donoho
+	// threshold for a known noise level.
+	KnownNoise = "known-noise"
+
+	// MarchenkoPastur is the upper edge of the
+	// Marchenko-Pastur bulk for the noise level.
+	MarchenkoPastur = "marchenko-pastur"
+)
+
+// Threshold specifies how the singular value threshold used to calculate
+// the optimal rank is determined. Threshold values must be created with
+// NewThreshold.
+type Threshold struct {
+	// method is the threshold method.
+	method string
+
+	// noise is the known noise level. If it is
+	// zero, the noise level is estimated from the
+	// singular values.
+	noise float64
+}
+
+// NewThreshold returns a Threshold using the given method and noise level.
+// The method must be one of UnknownNoise, KnownNoise or MarchenkoPastur.
+// A zero noise level indicates that the noise level should be estimated
+// from the median singular value. The UnknownNoise method always estimates
+// the noise level, so noise must be zero.
+func NewThreshold(method string, noise float64) (Threshold, error) {
+	switch method {
+	case UnknownNoise:
+		if noise != 0 {
+			return Threshold{}, fmt.Errorf("%s threshold does not take a noise level", UnknownNoise)
+		}
+	case MarchenkoPastur:
+	case KnownNoise:
+		if noise <= 0 {
+			return Threshold{}, fmt.Errorf("%s threshold requires a positive noise level", KnownNoise)
+		}
+	default:
+		return Threshold{}, fmt.Errorf("unknown threshold method: %q", method)
+	}
+	if noise < 0 {
+		return Threshold{}, fmt.Errorf("invalid noise level: %v", noise)
+	}
+	return Threshold{method: method, noise: noise}, nil
+}
+
+// tau returns the singular value threshold for a rows×cols matrix with the
+// singular values in values, sorted in descending order, and the noise level
+// used to calculate it. For the unknown-noise method, the returned noise
+// level is the estimate implied by the median singular value.
+//
+// If resid is not nil, values holds only the largest singular values of
+// the matrix and the median singular value is not known. In this case the
+// noise level is estimated from resid and the unknown-noise threshold is
+// the known-noise threshold for the estimated noise level.
+//
+// See https://arxiv.org/abs/1305.5870 Eq. 4, 10 and 11.
+func (t Threshold) tau(rows, cols int, values []float64, resid *residual) (tau, noise float64) {
+	if len(values) == 0 {
+		return 0, t.noise
+	}
+	beta := aspect(rows, cols)
+	n := float64(max(rows, cols))
+	noise = t.noise
+	if noise == 0 {
+		if resid != nil {
+			noise = resid.noise(rows, cols)
+		} else {
+			noise = median(values) / math.Sqrt(n*mpMedian(beta))
+		}
+	}
+	switch t.method {
+	case UnknownNoise:
+		if resid != nil {
+			return lambdaStar(beta) * math.Sqrt(n) * noise, noise
+		}
+		return omega(beta) * median(values), noise
+	case KnownNoise:
+		return lambdaStar(beta) * math.Sqrt(n) * noise, noise
+	case MarchenkoPastur:
+		return (1 + math.Sqrt(beta)) * math.Sqrt(n) * noise, noise
+	default:
+		panic("smear: invalid threshold method")
+	}
+}
+
+// aspect returns the aspect ratio of a rows×cols matrix, β, such that
+// 0 < β ≤ 1.
+func aspect(rows, cols int) float64 {
+	return float64(min(rows, cols)) / float64(max(rows, cols))
+}
+
+// median returns the median of the values, which must be sorted in
+// descending order.
+func median(values []float64) float64 {
+	reverseFloats(values)
+	m := stat.Quantile(0.5, 1, values, nil)
+	reverseFloats(values)
+	return m
+}
+
+func reverseFloats(f []float64) {
+	for i, j := 0, len(f)-1; i < j; i, j = i+1, j-1 {
+		f[i], f[j] = f[j], f[i]
+	}
+}
+
+// omega returns the optimal hard threshold coefficient for singular values
+// of a matrix with aspect ratio beta and unknown noise level.
+//
+// https://arxiv.org/abs/1305.5870 Eq. 10.
+func omega(beta float64) float64 {
+	return lambdaStar(beta) / math.Sqrt(mpMedian(beta))
+}
+
+// lambdaStar returns the optimal hard threshold coefficient for singular
+// values of a matrix with aspect ratio beta and known noise level.
+//
+// https://arxiv.org/abs/1305.5870 Eq. 11.
+func lambdaStar(beta float64) float64 {
+	return math.Sqrt(2*(beta+1) + 8*beta/((beta+1)+math.Sqrt(beta*beta+14*beta+1)))
+}
+
+// mpMedian returns the median of the Marchenko-Pastur distribution with
+// aspect ratio beta and unit variance.
+func mpMedian(beta float64) float64 {
+	at, density := mpSubstitution(beta)
+	return at(mpQuantileAngle(density, 0.5))
+}
+
+// mpLowerMoments returns the contributions of the lower p quantile of the
+// Marchenko-Pastur distribution with aspect ratio beta and unit variance
+// to the mean of the distribution and to the mean of its square root. For
+// a noise matrix, these are the contributions of its smallest singular
+// values to the mean of their squares and to their mean.
+func mpLowerMoments(beta, p float64) (mean, meanSqrt float64) {
+	at, density := mpSubstitution(beta)
+	theta := mpQuantileAngle(density, p)
+	mean = quad.Fixed(func(theta float64) float64 {
+		return at(theta) * density(theta)
+	}, 0, theta, 100, nil, 0)
+	meanSqrt = quad.Fixed(func(theta float64) float64 {
+		return math.Sqrt(at(theta)) * density(theta)
+	}, 0, theta, 100, nil, 0)
+	return mean, meanSqrt
+}
+
+// mpSubstitution returns the value and density of the Marchenko-Pastur
+// distribution with aspect ratio beta and unit variance as functions of
+// θ in [0, π], where the value is lo + half*(1-cos(θ)) for the support
+// [lo, lo+2*half]. The substitution removes the square root singularities
+// of the density at the edges of the support.
+func mpSubstitution(beta float64) (at, density func(theta float64) float64) {
+	lo := (1 - math.Sqrt(beta)) * (1 - math.Sqrt(beta))
+	hi := (1 + math.Sqrt(beta)) * (1 + math.Sqrt(beta))
+	half := (hi - lo) / 2
+	at = func(theta float64) float64 {
+		return lo + half*(1-math.Cos(theta))
+	}
+	density = func(theta float64) float64 {
+		sin := math.Sin(theta)
+		return half * half * sin * sin / (2 * math.Pi * beta * at(theta))
+	}
+	return at, density
+}
+
+// mpQuantileAngle returns the θ of the p quantile of the Marchenko-Pastur
+// density returned by mpSubstitution.
+func mpQuantileAngle(density func(float64) float64, p float64) float64 {
+	// Find the quantile by bisection on θ.
+	a, b := 0.0, math.Pi
+	for i := 0; i < 60; i++ {
+		mid := (a + b) / 2
+		if quad.Fixed(density, 0, mid, 100, nil, 0) < p {
+			a = mid
+		} else {
+			b = mid
+		}
+	}
+	return (a + b) / 2
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,281 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// lowRankMatrix returns a rows×cols matrix that is the sum of a signal
+// matrix with the given singular values and a noise matrix with
+// independent standard normal elements scaled by noise.
+func lowRankMatrix(rnd *rand.Rand, rows, cols int, signal []float64, noise float64) *Matrix {
+	d := mat.NewDense(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			d.Set(i, j, noise*rnd.NormFloat64())
+		}
+	}
+	if len(signal) == 0 {
+		return denseToMatrix(d)
+	}
+	u := randomOrthonormal(rnd, rows, len(signal))
+	v := randomOrthonormal(rnd, cols, len(signal))
+	for k, s := range signal {
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				d.Set(i, j, d.At(i, j)+s*u.At(i, k)*v.At(j, k))
+			}
+		}
+	}
+	return denseToMatrix(d)
+}
+
+func denseToMatrix(d *mat.Dense) *Matrix {
+	rows, cols := d.Dims()
+	m := newMatrix(rows, cols)
+	for j := 0; j < cols; j++ {
+		for i := 0; i < rows; i++ {
+			m.append(i, d.At(i, j))
+		}
+		m.endColumn()
+	}
+	return m
+}
+
+func randomOrthonormal(rnd *rand.Rand, n, k int) *mat.Dense {
+	q := mat.NewDense(n, k, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < k; j++ {
+			q.Set(i, j, rnd.NormFloat64())
+		}
+	}
+	orthonormalize(q)
+	return q
+}
+
+var truncatedRankTests = []struct {
+	name       string
+	rows, cols int
+	signal     []float64
+	noise      float64
+	rank       int
+	method     string
+	wantRank   int
+}{
+	{name: "square", rows: 200, cols: 200, signal: []float64{300, 200, 100}, noise: 1, rank: 20, method: UnknownNoise, wantRank: 3},
+	{name: "tall", rows: 400, cols: 100, signal: []float64{300, 200, 100, 60}, noise: 1, rank: 20, method: UnknownNoise, wantRank: 4},
+	{name: "wide", rows: 100, cols: 400, signal: []float64{300, 200, 100, 60}, noise: 2, rank: 10, method: UnknownNoise, wantRank: 4},
+	{name: "marchenko-pastur", rows: 400, cols: 100, signal: []float64{300, 200, 100, 60}, noise: 1, rank: 20, method: MarchenkoPastur, wantRank: 4},
+	{name: "dominant", rows: 300, cols: 150, signal: []float64{6000, 4000, 2000}, noise: 1, rank: 20, method: UnknownNoise, wantRank: 3},
+	{name: "flat", rows: 300, cols: 150, noise: 1, rank: 20, method: UnknownNoise, wantRank: 0},
+}
+
+func TestTruncatedRank(t *testing.T) {
+	const tol = 0.05
+
+	rnd := rand.New(rand.NewSource(1))
+	for _, test := range truncatedRankTests {
+		m := lowRankMatrix(rnd, test.rows, test.cols, test.signal, test.noise)
+		thresh, err := NewThreshold(test.method, 0)
+		if err != nil {
+			t.Fatalf("unexpected error creating threshold: %v", err)
+		}
+
+		full, err := Rank(m, RankOptions{Frac: 0.75, Threshold: thresh, SVDLimit: math.MaxInt32})
+		if err != nil {
+			t.Fatalf("unexpected error for full SVD of %s: %v", test.name, err)
+		}
+		partial, err := Rank(m, RankOptions{Frac: 0.75, Threshold: thresh, SVDLimit: 1, SVDRank: test.rank})
+		if err != nil {
+			t.Fatalf("unexpected error for truncated SVD of %s: %v", test.name, err)
+		}
+		if full.SigmaPartial || !partial.SigmaPartial {
+			t.Fatalf("unexpected partial spectrum state for %s: full:%t truncated:%t", test.name, full.SigmaPartial, partial.SigmaPartial)
+		}
+
+		if full.OptimalRank != test.wantRank {
+			t.Errorf("unexpected optimal rank for full SVD of %s: got:%d want:%d", test.name, full.OptimalRank, test.wantRank)
+		}
+		if partial.OptimalRank != full.OptimalRank {
+			t.Errorf("unexpected optimal rank for truncated SVD of %s: got:%d want:%d", test.name, partial.OptimalRank, full.OptimalRank)
+		}
+		if !withinRel(partial.Tau, full.Tau, tol) {
+			t.Errorf("unexpected threshold for truncated SVD of %s: got:%v want:%v", test.name, partial.Tau, full.Tau)
+		}
+		if !withinRel(partial.Noise, test.noise, tol) {
+			t.Errorf("unexpected noise estimate for truncated SVD of %s: got:%v want:%v", test.name, partial.Noise, test.noise)
+		}
+		if test.rank < full.FractionalRank {
+			continue
+		}
+		if partial.FractionalRank != full.FractionalRank {
+			t.Errorf("unexpected fractional rank for truncated SVD of %s: got:%d want:%d", test.name, partial.FractionalRank, full.FractionalRank)
+		}
+	}
+}
+
+func withinRel(got, want, tol float64) bool {
+	return math.Abs(got-want) <= tol*math.Abs(want)
+}
+
+// Reference values for the Marchenko-Pastur median and ω were calculated
+// by independent numerical integration of the Marchenko-Pastur density
+// and agree with Table 1 of https://arxiv.org/abs/1305.5870.
+var coefficientTests = []struct {
+	beta       float64
+	lambdaStar float64
+	mpMedian   float64
+	omega      float64
+}{
+	{beta: 1, lambdaStar: 4 / math.Sqrt(3), mpMedian: 0.652776, omega: 2.858},
+	{beta: 0.5, lambdaStar: 1.978599, mpMedian: 0.830466, omega: 2.171},
+	{beta: 0.25, lambdaStar: 1.758029, mpMedian: 0.916004, omega: 1.837},
+	{beta: 0.1, lambdaStar: 1.581648, mpMedian: 0.966565, omega: 1.609},
+}
+
+func TestCoefficients(t *testing.T) {
+	for _, test := range coefficientTests {
+		if got := lambdaStar(test.beta); !withinRel(got, test.lambdaStar, 1e-6) {
+			t.Errorf("unexpected λ*(%v): got:%v want:%v", test.beta, got, test.lambdaStar)
+		}
+		if got := mpMedian(test.beta); !withinRel(got, test.mpMedian, 1e-5) {
+			t.Errorf("unexpected Marchenko-Pastur median for β=%v: got:%v want:%v", test.beta, got, test.mpMedian)
+		}
+		if got := omega(test.beta); !withinRel(got, test.omega, 5e-4) {
+			t.Errorf("unexpected ω(%v): got:%v want:%v", test.beta, got, test.omega)
+		}
+	}
+}
+
+func TestMPLowerMoments(t *testing.T) {
+	for _, beta := range []float64{1, 0.5, 0.25, 0.1} {
+		mean, meanSqrt := mpLowerMoments(beta, 1)
+		// The Marchenko-Pastur distribution with unit
+		// variance has unit mean.
+		if !withinRel(mean, 1, 1e-6) {
+			t.Errorf("unexpected mean for β=%v: got:%v want:1", beta, mean)
+		}
+		// By Jensen's inequality E[√x] < √E[x].
+		if meanSqrt <= 0 || meanSqrt >= 1 {
+			t.Errorf("unexpected mean square root for β=%v: got:%v want in (0, 1)", beta, meanSqrt)
+		}
+		lowMean, lowMeanSqrt := mpLowerMoments(beta, 0.5)
+		if lowMean <= 0 || lowMean >= mean/2 {
+			t.Errorf("unexpected lower half mean for β=%v: got:%v want in (0, %v)", beta, lowMean, mean/2)
+		}
+		if lowMeanSqrt <= 0 || lowMeanSqrt >= meanSqrt/2 {
+			t.Errorf("unexpected lower half mean square root for β=%v: got:%v want in (0, %v)", beta, lowMeanSqrt, meanSqrt/2)
+		}
+	}
+}
+
+var newThresholdTests = []struct {
+	method  string
+	noise   float64
+	wantErr bool
+}{
+	{method: UnknownNoise, noise: 0},
+	{method: UnknownNoise, noise: 1, wantErr: true},
+	{method: KnownNoise, noise: 1},
+	{method: KnownNoise, noise: 0, wantErr: true},
+	{method: MarchenkoPastur, noise: 0},
+	{method: MarchenkoPastur, noise: 2},
+	{method: MarchenkoPastur, noise: -1, wantErr: true},
+	{method: UnknownNoise, noise: -1, wantErr: true},
+	{method: "median", noise: 0, wantErr: true},
+}
+
+func TestNewThreshold(t *testing.T) {
+	for _, test := range newThresholdTests {
+		_, err := NewThreshold(test.method, test.noise)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %s with noise %v: got:%v want error:%t", test.method, test.noise, err, test.wantErr)
+		}
+	}
+}
+
+// tauTestValues is a spectrum of 101 singular values, sorted in descending
+// order, with median 7.
+var tauTestValues = func() []float64 {
+	v := make([]float64, 101)
+	for i := range v {
+		v[i] = 12 - float64(i)/10
+	}
+	return v
+}()
+
+var tauTests = []struct {
+	name       string
+	method     string
+	noise      float64
+	rows, cols int
+	wantTau    float64
+	wantNoise  float64
+}{
+	{
+		name:   "unknown noise square",
+		method: UnknownNoise, rows: 101, cols: 101,
+		wantTau:   omega(1) * 7,
+		wantNoise: 7 / math.Sqrt(101*mpMedian(1)),
+	},
+	{
+		name:   "unknown noise tall",
+		method: UnknownNoise, rows: 404, cols: 101,
+		wantTau:   omega(0.25) * 7,
+		wantNoise: 7 / math.Sqrt(404*mpMedian(0.25)),
+	},
+	{
+		name:   "known noise",
+		method: KnownNoise, noise: 2, rows: 101, cols: 101,
+		wantTau:   4 / math.Sqrt(3) * math.Sqrt(101) * 2,
+		wantNoise: 2,
+	},
+	{
+		name:   "known noise wide",
+		method: KnownNoise, noise: 0.5, rows: 101, cols: 202,
+		wantTau:   lambdaStar(0.5) * math.Sqrt(202) * 0.5,
+		wantNoise: 0.5,
+	},
+	{
+		name:   "marchenko-pastur known noise",
+		method: MarchenkoPastur, noise: 2, rows: 101, cols: 404,
+		wantTau:   1.5 * math.Sqrt(404) * 2,
+		wantNoise: 2,
+	},
+	{
+		name:   "marchenko-pastur estimated noise",
+		method: MarchenkoPastur, rows: 101, cols: 101,
+		wantTau:   2 * math.Sqrt(101) * 7 / math.Sqrt(101*mpMedian(1)),
+		wantNoise: 7 / math.Sqrt(101*mpMedian(1)),
+	},
+}
+
+func TestTau(t *testing.T) {
+	for _, test := range tauTests {
+		thresh, err := NewThreshold(test.method, test.noise)
+		if err != nil {
+			t.Fatalf("unexpected error creating threshold for %s: %v", test.name, err)
+		}
+		values := append([]float64(nil), tauTestValues...)
+		tau, noise := thresh.tau(test.rows, test.cols, values, nil)
+		if !withinRel(tau, test.wantTau, 1e-12) {
+			t.Errorf("unexpected threshold for %s: got:%v want:%v", test.name, tau, test.wantTau)
+		}
+		if !withinRel(noise, test.wantNoise, 1e-12) {
+			t.Errorf("unexpected noise for %s: got:%v want:%v", test.name, noise, test.wantNoise)
+		}
+		for i := range values {
+			if values[i] != tauTestValues[i] {
+				t.Errorf("values modified by threshold calculation for %s", test.name)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/formats/rdf"
+
+	"github.com/kortschak/gogo"
+)
+
+// relationPredicates maps the names of the GO relations that may be
+// followed when smearing counts to their local predicate IRIs. The
+// has_part relation is deliberately absent since it does not satisfy
+// the GO true path rule in the direction of smearing.
+var relationPredicates = map[string]string{
+	"is_a":                 "<rdfs:subClassOf>",
+	"part_of":              "<obo:BFO_0000050>",
+	"regulates":            "<obo:RO_0002211>",
+	"negatively_regulates": "<obo:RO_0002212>",
+	"positively_regulates": "<obo:RO_0002213>",
+}
+
+// Relations is a set of predicates that define the edges of the GO DAG
+// that are followed during traversals.
+type Relations map[string]bool
+
+// ParseRelations returns the relations named in the comma-separated list.
+// The is_a relation is always included.
+func ParseRelations(list string) (Relations, error) {
+	rels := Relations{relationPredicates["is_a"]: true}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		pred, ok := relationPredicates[name]
+		if !ok {
+			var valid []string
+			for n := range relationPredicates {
+				valid = append(valid, n)
+			}
+			sort.Strings(valid)
+			return nil, fmt.Errorf("invalid relation %q: valid relations are %s", name, strings.Join(valid, ", "))
+		}
+		rels[pred] = true
+	}
+	return rels, nil
+}
+
+// RelationName returns the relation name for the predicate value pred.
+func RelationName(pred string) (name string, ok bool) {
+	for n, p := range relationPredicates {
+		if p == pred {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// isSubClassOfGO is a traverse edge filter. It accepts statements where
+//
+//  any -- <relation> -> <obo:GO_*
+//
+// for out queries from a term.
+func (r Relations) isSubClassOfGO(e graph.Edge) bool {
+	return gogo.ConnectedByAny(e, func(s *rdf.Statement) bool {
+		return r[s.Predicate.Value] &&
+			strings.HasPrefix(s.Object.Value, "<obo:GO_")
+	})
+}
+
+// goIsSubClassOf is a traverse edge filter. It accepts statements where
+//
+//  <obo:GO_* <- <relation> -- any
+//
+// for in queries from a term.
+func (r Relations) goIsSubClassOf(e graph.Edge) bool {
+	return gogo.ConnectedByAny(e, func(s *rdf.Statement) bool {
+		return r[s.Predicate.Value] &&
+			strings.HasPrefix(s.Subject.Value, "<obo:GO_")
+	})
+}
+
+// restrictions collects existential restrictions from an ontology
+// statement stream so that they can be reconstructed as direct edges
+// between GO terms.
+//
+//  <obo:GO_a> <rdfs:subClassOf> _:r .
+//  _:r <owl:onProperty> <relation> .
+//  _:r <owl:someValuesFrom> <obo:GO_b> .
+//
+// is reconstructed as
+//
+//  <obo:GO_a> <relation> <obo:GO_b> .
+//
+// only if GO_a and GO_b are in the same namespace. Restrictions between
+// aspects, such as a biological process that is part_of a cellular
+// component, are not reconstructed since the aspects are analysed
+// separately and painting counts across them would connect the roots.
+type restrictions struct {
+	subClassOf     map[string][]string
+	onProperty     map[string]string
+	someValuesFrom map[string]string
+
+	// namespace holds the hasOBONamespace
+	// value of each GO term.
+	namespace map[string]string
+}
+
+func newRestrictions() *restrictions {
+	return &restrictions{
+		subClassOf:     make(map[string][]string),
+		onProperty:     make(map[string]string),
+		someValuesFrom: make(map[string]string),
+		namespace:      make(map[string]string),
+	}
+}
+
+// record records the parts of restrictions held by s.
+func (r *restrictions) record(s *rdf.Statement) {
+	switch s.Predicate.Value {
+	case "<rdfs:subClassOf>":
+		if strings.HasPrefix(s.Subject.Value, "<obo:GO_") && strings.HasPrefix(s.Object.Value, "_:") {
+			r.subClassOf[s.Object.Value] = append(r.subClassOf[s.Object.Value], s.Subject.Value)
+		}
+	case "<owl:onProperty>":
+		r.onProperty[s.Subject.Value] = s.Object.Value
+	case "<owl:someValuesFrom>":
+		if strings.HasPrefix(s.Object.Value, "<obo:GO_") {
+			r.someValuesFrom[s.Subject.Value] = s.Object.Value
+		}
+	case "<oboInOwl:hasOBONamespace>":
+		if strings.HasPrefix(s.Subject.Value, "<obo:GO_") {
+			r.namespace[s.Subject.Value] = s.Object.Value
+		}
+	}
+}
+
+// addTo adds direct statements to dst for restrictions on the relations
+// in rels between terms in the same namespace.
+func (r *restrictions) addTo(dst *gogo.Graph, rels Relations) {
+	blanks := make([]string, 0, len(r.subClassOf))
+	for blank := range r.subClassOf {
+		blanks = append(blanks, blank)
+	}
+	sort.Strings(blanks)
+	for _, blank := range blanks {
+		subjects := r.subClassOf[blank]
+		pred, ok := r.onProperty[blank]
+		if !ok || !rels[pred] {
+			continue
+		}
+		obj, ok := r.someValuesFrom[blank]
+		if !ok {
+			continue
+		}
+		for _, subj := range subjects {
+			if r.namespace[subj] != r.namespace[obj] {
+				continue
+			}
+			dst.AddStatement(&rdf.Statement{
+				Subject:   rdf.Term{Value: subj},
+				Predicate: rdf.Term{Value: pred},
+				Object:    rdf.Term{Value: obj},
+			})
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+
+	"github.com/kortschak/gogo"
+)
+
+const testRelationsOBO = `format-version: 1.4
+ontology: go
+
+[Term]
+id: GO:0008150
+name: biological_process
+namespace: biological_process
+
+[Term]
+id: GO:0005575
+name: cellular_component
+namespace: cellular_component
+
+[Term]
+id: GO:0003674
+name: molecular_function
+namespace: molecular_function
+
+[Term]
+id: GO:0000001
+name: process part
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+relationship: part_of GO:0000002 ! process whole
+
+[Term]
+id: GO:0000002
+name: process whole
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+
+[Term]
+id: GO:0000003
+name: process in component
+namespace: biological_process
+is_a: GO:0008150 ! biological_process
+relationship: part_of GO:0000004 ! component
+
+[Term]
+id: GO:0000004
+name: component
+namespace: cellular_component
+is_a: GO:0005575 ! cellular_component
+
+[Term]
+id: GO:0000005
+name: function regulating process
+namespace: molecular_function
+is_a: GO:0003674 ! molecular_function
+relationship: regulates GO:0000001 ! process part
+
+[Typedef]
+id: part_of
+name: part of
+xref: BFO:0000050
+
+[Typedef]
+id: regulates
+name: regulates
+xref: RO:0002211
+`
+
+func TestCrossAspectRelations(t *testing.T) {
+	ctx := context.Background()
+
+	rels, err := ParseRelations("part_of,regulates")
+	if err != nil {
+		t.Fatalf("unexpected error parsing relations: %v", err)
+	}
+	o, err := LoadOntology(ctx, strings.NewReader(testRelationsOBO), true, rels)
+	if err != nil {
+		t.Fatalf("unexpected error loading ontology: %v", err)
+	}
+
+	wantTerms := map[string][]string{
+		"<obo:GO_0003674>": {"<obo:GO_0000005>", "<obo:GO_0003674>"},
+		"<obo:GO_0005575>": {"<obo:GO_0000004>", "<obo:GO_0005575>"},
+		"<obo:GO_0008150>": {"<obo:GO_0000001>", "<obo:GO_0000002>", "<obo:GO_0000003>", "<obo:GO_0008150>"},
+	}
+	roots := o.Roots()
+	if len(roots) != len(wantTerms) {
+		t.Fatalf("unexpected roots: %v", roots)
+	}
+	for _, r := range roots {
+		levels, err := Levels(ctx, o, r)
+		if err != nil {
+			t.Fatalf("unexpected error getting levels: %v", err)
+		}
+		var got []string
+		for _, l := range levels {
+			got = append(got, l.Terms...)
+		}
+		sort.Strings(got)
+		if want := wantTerms[r.Value]; !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected terms below %s: got:%v want:%v", r.Value, got, want)
+		}
+	}
+
+	data, err := ReadCounts(ctx, strings.NewReader("Geneid\tS1\nENSG00000000001\t1\nENSG00000000002\t1\n"), "", FormatTSV, QuantCounts)
+	if err != nil {
+		t.Fatalf("unexpected error reading counts: %v", err)
+	}
+	const annotations = `<obo:GO_0000003> <local:annotates> <ensembl:ENSG00000000001> .
+<obo:GO_0000005> <local:annotates> <ensembl:ENSG00000000002> .
+`
+	err = Annotate(ctx, o, strings.NewReader(annotations), data, EvidenceFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error annotating ontology: %v", err)
+	}
+	p, err := Distribute(ctx, o, data, 1)
+	if err != nil {
+		t.Fatalf("unexpected error distributing counts: %v", err)
+	}
+	wantGenes := map[string][]int{
+		"<obo:GO_0003674>": {1},
+		"<obo:GO_0005575>": nil,
+		"<obo:GO_0008150>": {0},
+	}
+	for i, r := range p.Roots() {
+		genes, _ := p.Genes(i, r.Value, 0)
+		if got, want := genes.Genes(), wantGenes[r.Value]; !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected genes painted onto %s: got:%v want:%v", r.Value, got, want)
+		}
+	}
+}
+
+var parseRelationsTests = []struct {
+	list    string
+	want    Relations
+	wantErr bool
+}{
+	{
+		list: "",
+		want: Relations{"<rdfs:subClassOf>": true},
+	},
+	{
+		list: "is_a",
+		want: Relations{"<rdfs:subClassOf>": true},
+	},
+	{
+		list: "part_of",
+		want: Relations{"<rdfs:subClassOf>": true, "<obo:BFO_0000050>": true},
+	},
+	{
+		list: " part_of, regulates ,",
+		want: Relations{"<rdfs:subClassOf>": true, "<obo:BFO_0000050>": true, "<obo:RO_0002211>": true},
+	},
+	{
+		list: "negatively_regulates,positively_regulates",
+		want: Relations{"<rdfs:subClassOf>": true, "<obo:RO_0002212>": true, "<obo:RO_0002213>": true},
+	},
+	{
+		list:    "has_part",
+		wantErr: true,
+	},
+	{
+		list:    "part_of,occurs_in",
+		wantErr: true,
+	},
+}
+
+func TestParseRelations(t *testing.T) {
+	for _, test := range parseRelationsTests {
+		got, err := ParseRelations(test.list)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %q: got:%v want error:%t", test.list, err, test.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected relations for %q: got:%v want:%v", test.list, got, test.want)
+		}
+	}
+}
+
+func TestRelationName(t *testing.T) {
+	for name, pred := range relationPredicates {
+		got, ok := RelationName(pred)
+		if !ok || got != name {
+			t.Errorf("unexpected name for %s: got:%q,%t want:%q,true", pred, got, ok, name)
+		}
+	}
+	got, ok := RelationName("<obo:BFO_0000051>")
+	if ok {
+		t.Errorf("unexpected name for has_part predicate: %q", got)
+	}
+}
+
+// testRestrictionOWL is the lean statement stream for GO_0000001, which is
+// a subclass of a restriction on each relation, and of a restriction on
+// has_part which is never reconstructed. All terms are in the same
+// namespace.
+const testRestrictionOWL = `<obo:GO_0000001> <oboInOwl:hasOBONamespace> "biological_process" .
+<obo:GO_0000002> <oboInOwl:hasOBONamespace> "biological_process" .
+<obo:GO_0000003> <oboInOwl:hasOBONamespace> "biological_process" .
+<obo:GO_0000004> <oboInOwl:hasOBONamespace> "biological_process" .
+<obo:GO_0000005> <oboInOwl:hasOBONamespace> "biological_process" .
+<obo:GO_0000006> <oboInOwl:hasOBONamespace> "biological_process" .
+<obo:GO_0000001> <rdfs:subClassOf> _:part_of .
+_:part_of <owl:onProperty> <obo:BFO_0000050> .
+_:part_of <owl:someValuesFrom> <obo:GO_0000002> .
+<obo:GO_0000001> <rdfs:subClassOf> _:regulates .
+_:regulates <owl:onProperty> <obo:RO_0002211> .
+_:regulates <owl:someValuesFrom> <obo:GO_0000003> .
+<obo:GO_0000001> <rdfs:subClassOf> _:negatively_regulates .
+_:negatively_regulates <owl:onProperty> <obo:RO_0002212> .
+_:negatively_regulates <owl:someValuesFrom> <obo:GO_0000004> .
+<obo:GO_0000001> <rdfs:subClassOf> _:positively_regulates .
+_:positively_regulates <owl:onProperty> <obo:RO_0002213> .
+_:positively_regulates <owl:someValuesFrom> <obo:GO_0000005> .
+<obo:GO_0000001> <rdfs:subClassOf> _:has_part .
+_:has_part <owl:onProperty> <obo:BFO_0000051> .
+_:has_part <owl:someValuesFrom> <obo:GO_0000006> .
+`
+
+var restrictionTests = []struct {
+	relations string
+	want      []string
+}{
+	{
+		relations: "",
+		want:      nil,
+	},
+	{
+		relations: "part_of",
+		want:      []string{"<obo:GO_0000001> <obo:BFO_0000050> <obo:GO_0000002>"},
+	},
+	{
+		relations: "regulates",
+		want:      []string{"<obo:GO_0000001> <obo:RO_0002211> <obo:GO_0000003>"},
+	},
+	{
+		relations: "negatively_regulates",
+		want:      []string{"<obo:GO_0000001> <obo:RO_0002212> <obo:GO_0000004>"},
+	},
+	{
+		relations: "positively_regulates",
+		want:      []string{"<obo:GO_0000001> <obo:RO_0002213> <obo:GO_0000005>"},
+	},
+	{
+		relations: "part_of,regulates,negatively_regulates,positively_regulates",
+		want: []string{
+			"<obo:GO_0000001> <obo:BFO_0000050> <obo:GO_0000002>",
+			"<obo:GO_0000001> <obo:RO_0002211> <obo:GO_0000003>",
+			"<obo:GO_0000001> <obo:RO_0002212> <obo:GO_0000004>",
+			"<obo:GO_0000001> <obo:RO_0002213> <obo:GO_0000005>",
+		},
+	},
+}
+
+func TestRestrictions(t *testing.T) {
+	for _, test := range restrictionTests {
+		rels, err := ParseRelations(test.relations)
+		if err != nil {
+			t.Fatalf("unexpected error parsing relations: %v", err)
+		}
+
+		r := newRestrictions()
+		dec := rdf.NewDecoder(strings.NewReader(testRestrictionOWL))
+		for {
+			s, err := dec.Unmarshal()
+			if err != nil {
+				if err != io.EOF {
+					t.Fatalf("unexpected error decoding statements: %v", err)
+				}
+				break
+			}
+			r.record(s)
+		}
+		g := gogo.NewGraph()
+		r.addTo(g, rels)
+
+		got := statementsOf(g)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected reconstructed relations for %q:\ngot: %q\nwant:%q", test.relations, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testCounts = `Geneid	S1	S2
+ENSG00000000001	3	0
+ENSG00000000002	5	7
+ENSG00000000003	1	1
+`
+
+	testAnnotations = `<obo:GO_0048646> <local:annotates> <ensembl:ENSG00000000001> .
+<obo:GO_0007267> <local:annotates> <ensembl:ENSG00000000002> .
+<obo:GO_0016829> <local:annotates> <ensembl:ENSG00000000002> .
+<obo:GO_0016829> <local:annotates> <ensembl:ENSG00000000004> .
+`
+)
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+
+	data, err := ReadCounts(ctx, strings.NewReader(testCounts), "", FormatAuto, QuantCounts)
+	if err != nil {
+		t.Fatalf("unexpected error reading counts: %v", err)
+	}
+	if !reflect.DeepEqual(data.Names, []string{"S1", "S2"}) {
+		t.Errorf("unexpected sample names: %q", data.Names)
+	}
+
+	f, err := os.Open("../owl/testdata/goslim_generic.owl.gz")
+	if err != nil {
+		t.Fatalf("failed to open ontology: %v", err)
+	}
+	defer f.Close()
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to open ontology: %v", err)
+	}
+	rels, err := ParseRelations("")
+	if err != nil {
+		t.Fatalf("unexpected error parsing relations: %v", err)
+	}
+	o, err := LoadOntology(ctx, z, true, rels)
+	if err != nil {
+		t.Fatalf("unexpected error loading ontology: %v", err)
+	}
+
+	err = Annotate(ctx, o, strings.NewReader(testAnnotations), data, EvidenceFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error annotating ontology: %v", err)
+	}
+
+	p, err := Distribute(ctx, o, data, 2)
+	if err != nil {
+		t.Fatalf("unexpected error distributing counts: %v", err)
+	}
+	if got, want := p.Unannotated(), []string{"ENSG00000000003"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected unannotated genes: got:%q want:%q", got, want)
+	}
+
+	bp := -1
+	for i, r := range p.Roots() {
+		if r.Value == "<obo:GO_0008150>" {
+			bp = i
+			break
+		}
+	}
+	if bp < 0 {
+		t.Fatalf("biological_process root not found in %v", p.Roots())
+	}
+	if ns := o.Namespace("<obo:GO_0008150>"); ns != "biological_process" {
+		t.Errorf("unexpected namespace for root: %q", ns)
+	}
+
+	for _, test := range []struct {
+		term   string
+		sample int
+		want   []int
+	}{
+		{term: "<obo:GO_0008150>", sample: 0, want: []int{0, 1}},
+		{term: "<obo:GO_0008150>", sample: 1, want: []int{1}},
+		{term: "<obo:GO_0048646>", sample: 0, want: []int{0}},
+		{term: "<obo:GO_0048646>", sample: 1, want: nil},
+		{term: "<obo:GO_0007267>", sample: 1, want: []int{1}},
+	} {
+		set, ok := p.Genes(bp, test.term, test.sample)
+		if !ok {
+			t.Errorf("expected %s to be painted", test.term)
+		}
+		if got := set.Genes(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected genes for %s in sample %d: got:%v want:%v", test.term, test.sample, got, test.want)
+		}
+	}
+	if _, ok := p.Genes(bp, "<obo:GO_0016829>", 0); ok {
+		t.Error("unexpected painting of molecular_function term below biological_process root")
+	}
+
+	levels, err := Levels(ctx, o, p.Roots()[bp])
+	if err != nil {
+		t.Fatalf("unexpected error getting levels: %v", err)
+	}
+	if len(levels) < 2 {
+		t.Fatalf("unexpected number of levels: %d", len(levels))
+	}
+	if !reflect.DeepEqual(levels[0], Level{Depth: 0, Terms: []string{"<obo:GO_0008150>"}}) {
+		t.Errorf("unexpected root level: %+v", levels[0])
+	}
+	var found bool
+	for _, term := range levels[1].Terms {
+		if term == "<obo:GO_0048646>" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("expected GO:0048646 in first level: %v", levels[1].Terms)
+	}
+
+	thresh, err := NewThreshold(UnknownNoise, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating threshold: %v", err)
+	}
+	opts := RankOptions{Cut: 1, Frac: 0.75, Threshold: thresh, SVDLimit: 1e7, SVDRank: 100}
+
+	m := p.GeneMatrix(bp, 0, levels[1].Terms)
+	if r, c := m.Dims(); r != len(data.GeneIDs) || c != len(levels[1].Terms) {
+		t.Errorf("unexpected gene matrix dimensions: got:%d×%d want:%d×%d", r, c, len(data.GeneIDs), len(levels[1].Terms))
+	}
+	s, err := Rank(m, opts)
+	if err != nil {
+		t.Fatalf("unexpected error ranking gene matrix: %v", err)
+	}
+	if s.Rows != len(data.GeneIDs) || s.Cols != len(levels[1].Terms) || s.Threshold != UnknownNoise {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+
+	m = p.TermMatrix(bp, levels[0].Terms)
+	var buf strings.Builder
+	err = m.WriteTSV(&buf, []string{"GO:0008150"}, data.Names)
+	if err != nil {
+		t.Fatalf("unexpected error writing term matrix: %v", err)
+	}
+	if got, want := buf.String(), "\tS1\tS2\nGO:0008150\t8\t7\n"; got != want {
+		t.Errorf("unexpected term matrix:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Matrix is a compressed sparse column matrix holding a level matrix.
+// Matrix values are constructed by a Painting.
+type Matrix struct {
+	rows, cols int
+
+	// colPtr holds the offsets into rowIdx
+	// and values for the start of each
+	// column, and the end of the last
+	// closed column.
+	colPtr []int
+	rowIdx []int
+	values []float64
+}
+
+var _ mat.Matrix = (*Matrix)(nil)
+
+// newMatrix returns an empty sparse matrix with the given dimensions.
+// Columns are built in order by appending non-zero values to the last
+// column and then closing the column with endColumn.
+func newMatrix(rows, cols int) *Matrix {
+	return &Matrix{rows: rows, cols: cols, colPtr: make([]int, 1, cols+1)}
+}
+
+// append adds the value v at row r of the column being built. Rows must
+// be appended in increasing order and zero values are not stored.
+func (m *Matrix) append(r int, v float64) {
+	if v == 0 {
+		return
+	}
+	m.rowIdx = append(m.rowIdx, r)
+	m.values = append(m.values, v)
+}
+
+// endColumn closes the column being built.
+func (m *Matrix) endColumn() {
+	m.colPtr = append(m.colPtr, len(m.rowIdx))
+}
+
+// Dims returns the dimensions of the matrix.
+func (m *Matrix) Dims() (r, c int) { return m.rows, m.cols }
+
+// At returns the value of the element at row i and column j.
+func (m *Matrix) At(i, j int) float64 {
+	if uint(i) >= uint(m.rows) {
+		panic(mat.ErrRowAccess)
+	}
+	if uint(j) >= uint(m.cols) {
+		panic(mat.ErrColAccess)
+	}
+	if j+1 >= len(m.colPtr) {
+		return 0
+	}
+	rows := m.rowIdx[m.colPtr[j]:m.colPtr[j+1]]
+	k := sort.SearchInts(rows, i)
+	if k == len(rows) || rows[k] != i {
+		return 0
+	}
+	return m.values[m.colPtr[j]+k]
+}
+
+// T returns the transpose of the matrix.
+func (m *Matrix) T() mat.Matrix { return mat.Transpose{Matrix: m} }
+
+// nnz returns the number of stored non-zero values.
+func (m *Matrix) nnz() int { return len(m.values) }
+
+// doNonZero calls fn for each non-zero element of the matrix in column
+// major order.
+func (m *Matrix) doNonZero(fn func(i, j int, v float64)) {
+	for j := 0; j+1 < len(m.colPtr); j++ {
+		for k := m.colPtr[j]; k < m.colPtr[j+1]; k++ {
+			fn(m.rowIdx[k], j, m.values[k])
+		}
+	}
+}
+
+// sumSquares returns the sum of the squares of the elements of the matrix,
+// the square of its Frobenius norm.
+func (m *Matrix) sumSquares() float64 {
+	return floats.Dot(m.values, m.values)
+}
+
+// dense returns a dense copy of the matrix.
+func (m *Matrix) dense() *mat.Dense {
+	d := mat.NewDense(m.rows, m.cols, nil)
+	m.doNonZero(d.Set)
+	return d
+}
+
+// scaleRows returns a copy of the matrix with each row i scaled by f[i].
+func (m *Matrix) scaleRows(f []float64) *Matrix {
+	s := newMatrix(m.rows, m.cols)
+	for j := 0; j+1 < len(m.colPtr); j++ {
+		for k := m.colPtr[j]; k < m.colPtr[j+1]; k++ {
+			s.append(m.rowIdx[k], f[m.rowIdx[k]]*m.values[k])
+		}
+		s.endColumn()
+	}
+	return s
+}
+
+// mulTo stores the product of the matrix and x in dst.
+func (m *Matrix) mulTo(dst, x *mat.Dense) {
+	dst.Zero()
+	m.doNonZero(func(i, j int, v float64) {
+		floats.AddScaled(dst.RawRowView(i), v, x.RawRowView(j))
+	})
+}
+
+// mulTransTo stores the product of the transpose of the matrix and x
+// in dst.
+func (m *Matrix) mulTransTo(dst, x *mat.Dense) {
+	dst.Zero()
+	m.doNonZero(func(i, j int, v float64) {
+		floats.AddScaled(dst.RawRowView(j), v, x.RawRowView(i))
+	})
+}
+
+// WriteTSV writes the matrix to w as a tab-delimited table with the given
+// row and column names.
+func (m *Matrix) WriteTSV(w io.Writer, rows, cols []string) error {
+	_, err := fmt.Fprintf(w, "\t%s\n", strings.Join(cols, "\t"))
+	if err != nil {
+		return err
+	}
+	for r, id := range rows {
+		_, err = io.WriteString(w, id)
+		if err != nil {
+			return err
+		}
+		for c := range cols {
+			_, err = fmt.Fprintf(w, "\t%v", m.At(r, c))
+			if err != nil {
+				return err
+			}
+		}
+		_, err = io.WriteString(w, "\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarket writes the non-zero elements of the matrix to w in Matrix
+// Market coordinate format.
+func (m *Matrix) WriteMarket(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%%%%MatrixMarket matrix coordinate real general\n%d %d %d\n", m.rows, m.cols, m.nnz())
+	if err != nil {
+		return err
+	}
+	m.doNonZero(func(i, j int, v float64) {
+		if err != nil {
+			return
+		}
+		// Matrix Market indices are one-based.
+		_, err = fmt.Fprintf(w, "%d %d %v\n", i+1, j+1, v)
+	})
+	return err
+}
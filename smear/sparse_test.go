@@ -0,0 +1,84 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"bytes"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+var writeMarketTests = []struct {
+	name string
+	m    *mat.Dense
+	want string
+}{
+	{
+		name: "zero",
+		m:    mat.NewDense(2, 3, nil),
+		want: `%%MatrixMarket matrix coordinate real general
+2 3 0
+`,
+	},
+	{
+		name: "sparse",
+		m: mat.NewDense(3, 2, []float64{
+			0, 1.5,
+			2, 0,
+			0, 3,
+		}),
+		want: `%%MatrixMarket matrix coordinate real general
+3 2 3
+2 1 2
+1 2 1.5
+3 2 3
+`,
+	},
+	{
+		name: "dense",
+		m: mat.NewDense(2, 2, []float64{
+			1, 2,
+			3, 4,
+		}),
+		want: `%%MatrixMarket matrix coordinate real general
+2 2 4
+1 1 1
+2 1 3
+1 2 2
+2 2 4
+`,
+	},
+}
+
+func TestWriteMarket(t *testing.T) {
+	for _, test := range writeMarketTests {
+		var buf bytes.Buffer
+		err := denseToMatrix(test.m).WriteMarket(&buf)
+		if err != nil {
+			t.Errorf("unexpected error writing %q: %v", test.name, err)
+			continue
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("unexpected output for %q:\ngot:\n%s\nwant:\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWriteTSV(t *testing.T) {
+	m := denseToMatrix(mat.NewDense(2, 3, []float64{
+		0, 1.5, 2,
+		3, 0, 0,
+	}))
+	var buf bytes.Buffer
+	err := m.WriteTSV(&buf, []string{"r1", "r2"}, []string{"c1", "c2", "c3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	const want = "\tc1\tc2\tc3\nr1\t0\t1.5\t2\nr2\t3\t0\t0\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected output:\ngot: %q\nwant:%q", got, want)
+	}
+}
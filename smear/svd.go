@@ -0,0 +1,108 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"errors"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Randomized SVD parameters.
+const (
+	// svdOversample is the number of additional
+	// random samples taken beyond the requested
+	// rank.
+	svdOversample = 10
+
+	// svdPowerIters is the number of subspace
+	// iterations used to sharpen the estimated
+	// range of the matrix.
+	svdPowerIters = 4
+
+	// svdSeed is the seed for the random test
+	// matrix so that results are reproducible.
+	svdSeed = 1
+)
+
+// truncatedValues returns the k largest singular values of m calculated
+// using the randomized range finder with subspace iteration described by
+// Halko, Martinsson and Tropp https://arxiv.org/abs/0909.4061 Algorithm 4.4
+// and 5.1.
+func truncatedValues(m *Matrix, k int) ([]float64, error) {
+	rows, cols := m.Dims()
+	l := k + svdOversample
+	if n := min(rows, cols); l > n {
+		l = n
+	}
+
+	rnd := rand.New(rand.NewSource(svdSeed))
+	omega := mat.NewDense(cols, l, nil)
+	for i := 0; i < cols; i++ {
+		row := omega.RawRowView(i)
+		for j := range row {
+			row[j] = rnd.NormFloat64()
+		}
+	}
+
+	q := mat.NewDense(rows, l, nil)
+	m.mulTo(q, omega)
+	orthonormalize(q)
+	z := omega
+	for i := 0; i < svdPowerIters; i++ {
+		m.mulTransTo(z, q)
+		orthonormalize(z)
+		m.mulTo(q, z)
+		orthonormalize(q)
+	}
+
+	// The singular values of (Aᵀ Q) are the
+	// singular values of B = Qᵀ A.
+	m.mulTransTo(z, q)
+	var svd mat.SVD
+	ok := svd.Factorize(z, mat.SVDNone)
+	if !ok {
+		return nil, errors.New("could not factorise projected matrix")
+	}
+	sigma := svd.Values(nil)
+	if len(sigma) > k {
+		sigma = sigma[:k]
+	}
+	return sigma, nil
+}
+
+// orthonormalize replaces the columns of a with an orthonormal basis for
+// their span using modified Gram-Schmidt with reorthogonalization. Columns
+// that are numerically dependent on earlier columns are set to zero.
+func orthonormalize(a *mat.Dense) {
+	_, c := a.Dims()
+	for j := 0; j < c; j++ {
+		v := a.ColView(j).(*mat.VecDense)
+		orig := mat.Norm(v, 2)
+		if orig == 0 {
+			continue
+		}
+		for pass := 0; pass < 2; pass++ {
+			for i := 0; i < j; i++ {
+				u := a.ColView(i)
+				v.AddScaledVec(v, -mat.Dot(u, v), u)
+			}
+		}
+		n := mat.Norm(v, 2)
+		if n <= 1e-12*orig {
+			v.Zero()
+			continue
+		}
+		v.ScaleVec(1/n, v)
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,133 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"gonum.org/v1/gonum/graph/formats/rdf"
+)
+
+// StripVersion returns the Ensembl identifier id without its version
+// suffix. ENSG00000141510.17 is returned as ENSG00000141510. Identifiers
+// without a numeric version suffix are returned unaltered.
+func StripVersion(id string) string {
+	i := strings.LastIndexByte(id, '.')
+	if i < 0 || i == len(id)-1 {
+		return id
+	}
+	for _, c := range id[i+1:] {
+		if c < '0' || '9' < c {
+			return id
+		}
+	}
+	return id[:i]
+}
+
+// ReadTranscriptGenes returns a mapping from transcript identifiers to gene
+// identifiers held in r. The data may be a tab-delimited
+// tx2gene table with the transcript identifier in the first column and
+// the gene identifier in the second, or RDF N-Triples in the form:
+//
+//   <transcript:ENST00000000000> <obo:SO_transcribed_from> <ensembl:ENSG00000000000> .
+//
+// as read by goglinks. Full Ensembl IRIs are also accepted. If strip is
+// true, version suffixes are removed from both transcript and gene
+// identifiers.
+func ReadTranscriptGenes(r io.Reader, strip bool) (map[string]string, error) {
+	br := bufio.NewReader(r)
+
+	tx2gene := make(map[string]string)
+	add := func(tx, gene string) {
+		if strip {
+			tx = StripVersion(tx)
+			gene = StripVersion(gene)
+		}
+		tx2gene[tx] = gene
+	}
+
+	if firstNonSpaceIs(br, '<') {
+		dec := rdf.NewDecoder(br)
+		for {
+			s, err := dec.Unmarshal()
+			if err != nil {
+				if err != io.EOF {
+					return nil, err
+				}
+				return tx2gene, nil
+			}
+			switch s.Predicate.Value {
+			case "<obo:SO_transcribed_from>", "<http://purl.obolibrary.org/obo/SO_transcribed_from>":
+				add(idOf(s.Subject.Value), idOf(s.Object.Value))
+			}
+		}
+	}
+
+	c := csv.NewReader(br)
+	c.Comma = '\t'
+	c.Comment = '#'
+	c.FieldsPerRecord = -1
+	c.ReuseRecord = true
+	for {
+		rec, err := c.Read()
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			return tx2gene, nil
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("invalid tx2gene record %q: expected transcript and gene identifiers", rec)
+		}
+		// A header line, if present, adds an unused mapping.
+		add(rec[0], rec[1])
+	}
+}
+
+// idOf returns the identifier part of an IRI term in either the local
+// <namespace:ID> form or the full <http://.../ID> form.
+func idOf(iri string) string {
+	iri = strip(iri, "<", ">")
+	if i := strings.LastIndexAny(iri, "/:"); i >= 0 {
+		return iri[i+1:]
+	}
+	return iri
+}
+
+// Aggregate returns the count data in d with each feature renamed by the
+// rename function. Counts for features that share a name after renaming
+// are summed. Feature order is retained in order of the first appearance
+// of each name.
+func (d *CountData) Aggregate(rename func(id string) string) *CountData {
+	agg := &CountData{
+		Names:   d.Names,
+		Counts:  make(map[string][]float64, len(d.Counts)),
+		geneIdx: make(map[string]int),
+	}
+	seen := make(map[string]bool)
+	for _, id := range d.GeneIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		name := rename(id)
+		sum, ok := agg.Counts[name]
+		if !ok {
+			sum = make([]float64, len(d.Names))
+			agg.Counts[name] = sum
+			agg.geneIdx[name] = len(agg.GeneIDs)
+			agg.GeneIDs = append(agg.GeneIDs, name)
+		}
+		counts := d.Counts[id]
+		for i := range sum {
+			sum[i] += counts[i]
+		}
+	}
+	return agg
+}
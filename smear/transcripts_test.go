@@ -0,0 +1,191 @@
+// Copyright ©2021 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smear
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var stripVersionTests = []struct {
+	id   string
+	want string
+}{
+	{id: "ENSG00000141510.17", want: "ENSG00000141510"},
+	{id: "ENSG00000141510", want: "ENSG00000141510"},
+	{id: "ENST00000269305.9", want: "ENST00000269305"},
+	{id: "ENSG00000141510.", want: "ENSG00000141510."},
+	{id: "ENSG00000141510.a1", want: "ENSG00000141510.a1"},
+	{id: "ENSG00000141510.1.2", want: "ENSG00000141510.1"},
+	{id: "ENSG00000141510_PAR_Y", want: "ENSG00000141510_PAR_Y"},
+	{id: "", want: ""},
+}
+
+func TestStripVersion(t *testing.T) {
+	for _, test := range stripVersionTests {
+		got := StripVersion(test.id)
+		if got != test.want {
+			t.Errorf("unexpected result for %q: got:%q want:%q", test.id, got, test.want)
+		}
+	}
+}
+
+var readTranscriptGenesTests = []struct {
+	name    string
+	doc     string
+	strip   bool
+	want    map[string]string
+	wantErr bool
+}{
+	{
+		name: "tsv",
+		doc: `ENST00000000001.1	ENSG00000000001.3
+ENST00000000002.2	ENSG00000000001.3
+# comment
+ENST00000000003.1	ENSG00000000002.1	extra
+`,
+		want: map[string]string{
+			"ENST00000000001.1": "ENSG00000000001.3",
+			"ENST00000000002.2": "ENSG00000000001.3",
+			"ENST00000000003.1": "ENSG00000000002.1",
+		},
+	},
+	{
+		name: "tsv strip",
+		doc: `TXNAME	GENEID
+ENST00000000001.1	ENSG00000000001.3
+ENST00000000002.2	ENSG00000000001.3
+`,
+		strip: true,
+		want: map[string]string{
+			"TXNAME":          "GENEID",
+			"ENST00000000001": "ENSG00000000001",
+			"ENST00000000002": "ENSG00000000001",
+		},
+	},
+	{
+		name:    "tsv short",
+		doc:     "ENST00000000001.1\n",
+		wantErr: true,
+	},
+	{
+		name: "ntriples",
+		doc: `<transcript:ENST00000000001> <obo:SO_transcribed_from> <ensembl:ENSG00000000001> .
+<http://rdf.ebi.ac.uk/resource/ensembl.transcript/ENST00000000002> <http://purl.obolibrary.org/obo/SO_transcribed_from> <http://rdf.ebi.ac.uk/resource/ensembl/ENSG00000000001> .
+<transcript:ENST00000000003> <rdfs:label> "transcript" .
+`,
+		want: map[string]string{
+			"ENST00000000001": "ENSG00000000001",
+			"ENST00000000002": "ENSG00000000001",
+		},
+	},
+	{
+		name: "ntriples strip",
+		doc: `
+<transcript:ENST00000000001.4> <obo:SO_transcribed_from> <ensembl:ENSG00000000001.2> .
+`,
+		strip: true,
+		want: map[string]string{
+			"ENST00000000001": "ENSG00000000001",
+		},
+	},
+	{
+		name:    "ntriples invalid",
+		doc:     "<transcript:ENST00000000001> <obo:SO_transcribed_from>\n",
+		wantErr: true,
+	},
+}
+
+func TestReadTranscriptGenes(t *testing.T) {
+	for _, test := range readTranscriptGenesTests {
+		got, err := ReadTranscriptGenes(strings.NewReader(test.doc), test.strip)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %q: got:%v want error:%t", test.name, err, test.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected mapping for %q:\ngot: %v\nwant:%v", test.name, got, test.want)
+		}
+	}
+}
+
+const testTranscriptCounts = `Geneid	S1	S2
+ENST00000000001.1	1	2
+ENST00000000002.2	3	4
+ENST00000000003.1	5	6
+ENST00000000004.1	7	8
+`
+
+var aggregateTests = []struct {
+	name      string
+	rename    func(string) string
+	wantIDs   []string
+	wantCount map[string][]float64
+}{
+	{
+		name:    "identity",
+		rename:  func(id string) string { return id },
+		wantIDs: []string{"ENST00000000001.1", "ENST00000000002.2", "ENST00000000003.1", "ENST00000000004.1"},
+		wantCount: map[string][]float64{
+			"ENST00000000001.1": {1, 2},
+			"ENST00000000002.2": {3, 4},
+			"ENST00000000003.1": {5, 6},
+			"ENST00000000004.1": {7, 8},
+		},
+	},
+	{
+		name:    "strip version",
+		rename:  StripVersion,
+		wantIDs: []string{"ENST00000000001", "ENST00000000002", "ENST00000000003", "ENST00000000004"},
+		wantCount: map[string][]float64{
+			"ENST00000000001": {1, 2},
+			"ENST00000000002": {3, 4},
+			"ENST00000000003": {5, 6},
+			"ENST00000000004": {7, 8},
+		},
+	},
+	{
+		name: "tx2gene",
+		rename: func(id string) string {
+			return map[string]string{
+				"ENST00000000001": "ENSG00000000002",
+				"ENST00000000002": "ENSG00000000001",
+				"ENST00000000003": "ENSG00000000002",
+				"ENST00000000004": "ENSG00000000001",
+			}[StripVersion(id)]
+		},
+		wantIDs: []string{"ENSG00000000002", "ENSG00000000001"},
+		wantCount: map[string][]float64{
+			"ENSG00000000001": {10, 12},
+			"ENSG00000000002": {6, 8},
+		},
+	},
+}
+
+func TestAggregate(t *testing.T) {
+	data, err := ReadCounts(context.Background(), strings.NewReader(testTranscriptCounts), "", FormatTSV, QuantCounts)
+	if err != nil {
+		t.Fatalf("unexpected error reading counts: %v", err)
+	}
+	for _, test := range aggregateTests {
+		got := data.Aggregate(test.rename)
+		if !reflect.DeepEqual(got.Names, data.Names) {
+			t.Errorf("unexpected sample names for %q: got:%q want:%q", test.name, got.Names, data.Names)
+		}
+		if !reflect.DeepEqual(got.GeneIDs, test.wantIDs) {
+			t.Errorf("unexpected gene IDs for %q: got:%q want:%q", test.name, got.GeneIDs, test.wantIDs)
+		}
+		if !reflect.DeepEqual(got.Counts, test.wantCount) {
+			t.Errorf("unexpected counts for %q: got:%v want:%v", test.name, got.Counts, test.wantCount)
+		}
+	}
+	if got := data.Counts["ENST00000000001.1"]; !reflect.DeepEqual(got, []float64{1, 2}) {
+		t.Errorf("aggregation altered source counts: got:%v", got)
+	}
+}